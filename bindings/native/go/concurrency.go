@@ -0,0 +1,108 @@
+// Copyright 2020-2022 IOTA Stiftung
+// SPDX-License-Identifier: Apache-2.0
+package stronghold
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// cancelTokenCounter hands out process-unique, non-zero tokens so the Rust
+// core can tell which in-flight operation a Cancel call refers to.
+var cancelTokenCounter uint64
+
+// newCancelToken returns a token identifying a single cancellable
+// operation. The native side is free to ignore a token it never saw
+// tagged to an operation, so it is safe to mint one for calls whose
+// Transport method does not currently forward it anywhere. Note that only
+// CreateSnapshot, LoadSnapshot, ExecuteProcedure and ChangePassword thread
+// their token through to the transport today - these are the calls that
+// can run long enough (Argon2id, a batched procedure) to be worth
+// aborting on the native side. Every other *Context method still returns
+// promptly on ctx cancellation, but the underlying transport call is left
+// to finish on its own; Close waits for it via inFlight rather than
+// leaving it to race s.handle.
+func newCancelToken() uint64 {
+	return atomic.AddUint64(&cancelTokenCounter, 1)
+}
+
+// recordLockEntry is a reference-counted mutex: refCount tracks how many
+// goroutines currently hold or are waiting on mu, so the entry can be
+// removed from StrongholdNative.recordLocks as soon as nobody needs it
+// anymore, instead of accumulating one entry per recordPath ever signed
+// for the life of the instance.
+type recordLockEntry struct {
+	mu       sync.Mutex
+	refCount int
+}
+
+// lockRecord locks the mutex guarding recordPath, creating it on first use,
+// and returns a function that unlocks it and, once nobody else is waiting
+// on it, removes it from s.recordLocks. Locking per recordPath, rather
+// than one lock for the whole instance, is what lets concurrent Sign
+// calls on different keys run without serializing behind each other.
+func (s *StrongholdNative) lockRecord(recordPath string) func() {
+	s.recordLocksMu.Lock()
+	entry, ok := s.recordLocks[recordPath]
+	if !ok {
+		entry = &recordLockEntry{}
+		s.recordLocks[recordPath] = entry
+	}
+	entry.refCount++
+	s.recordLocksMu.Unlock()
+
+	entry.mu.Lock()
+
+	return func() {
+		entry.mu.Unlock()
+
+		s.recordLocksMu.Lock()
+		entry.refCount--
+		if entry.refCount == 0 {
+			delete(s.recordLocks, recordPath)
+		}
+		s.recordLocksMu.Unlock()
+	}
+}
+
+// runContext runs fn on a goroutine, tracked in s.inFlight so Close can
+// wait for it to finish, and returns as soon as either fn completes or ctx
+// is cancelled. On cancellation it asks the transport to abort the
+// operation tagged with token and returns ctx.Err() without waiting for
+// fn; fn keeps running in the background; and its result, once fn does
+// return, is discarded. s.inFlight.Wait() (called from Close) is what
+// stops the now-orphaned goroutine from touching s.handle after Destroy
+// runs. If ctx is already done on entry, fn is not run at all. The
+// transport's Cancel is called synchronously, so the return on
+// cancellation waits for it (ipcTransport's Cancel waits for its
+// in-progress call to finish).
+func runContext[T any](ctx context.Context, s *StrongholdNative, token uint64, fn func() (T, error)) (T, error) {
+	type result struct {
+		value T
+		err   error
+	}
+
+	if err := ctx.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	done := make(chan result, 1)
+
+	s.inFlight.Add(1)
+	go func() {
+		defer s.inFlight.Done()
+		value, err := fn()
+		done <- result{value, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		s.transport.Cancel(s.handle, token)
+		var zero T
+		return zero, ctx.Err()
+	}
+}
@@ -0,0 +1,10 @@
+// Copyright 2020-2022 IOTA Stiftung
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build cgo
+
+package stronghold
+
+func defaultTransport() Transport {
+	return cgoTransport{}
+}
@@ -0,0 +1,79 @@
+// Copyright 2020-2022 IOTA Stiftung
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !cgo
+
+package stronghold
+
+import "errors"
+
+// The key and signature sizes are otherwise declared in native.go, which is
+// only compiled with cgo.
+const SignatureSize = 64
+const PublicKeySize = 32
+
+const SignatureSizeSecp256k1 = 65
+const PublicKeySizeSecp256k1 = 33
+
+// noTransport reports a descriptive error for every call instead of
+// linking the cgo core, which is unavailable in a CGO_ENABLED=0 build.
+// Callers must pass WithTransport(an ipcTransport or a custom Transport)
+// to NewStronghold in this configuration.
+type noTransport struct{}
+
+var errNoTransport = errors.New("no transport configured: this binary was built without cgo, pass WithTransport(...) to NewStronghold")
+
+func (noTransport) CreateSnapshot(string, string, uint64) (SessionHandle, error) {
+	return nil, errNoTransport
+}
+func (noTransport) LoadSnapshot(string, string, uint64) (SessionHandle, error) {
+	return nil, errNoTransport
+}
+func (noTransport) GenerateED25519KeyPair(SessionHandle, string, string) ([PublicKeySize]byte, error) {
+	return [PublicKeySize]byte{}, errNoTransport
+}
+func (noTransport) Sign(SessionHandle, string, []byte) ([SignatureSize]byte, error) {
+	return [SignatureSize]byte{}, errNoTransport
+}
+func (noTransport) GetPublicKey(SessionHandle, string) ([PublicKeySize]byte, error) {
+	return [PublicKeySize]byte{}, errNoTransport
+}
+func (noTransport) GenerateSeed(SessionHandle, string) (bool, error) { return false, errNoTransport }
+func (noTransport) DeriveSeed(SessionHandle, string, uint32) (bool, error) {
+	return false, errNoTransport
+}
+func (noTransport) GenerateMnemonic(int) (string, error) { return "", errNoTransport }
+func (noTransport) ImportMnemonic(SessionHandle, string, string, string, string) error {
+	return errNoTransport
+}
+func (noTransport) ExportMnemonic(SessionHandle, string, string) (string, error) {
+	return "", errNoTransport
+}
+func (noTransport) DerivePath(SessionHandle, string, string, []uint32, string) error {
+	return errNoTransport
+}
+func (noTransport) ExecuteProcedure(SessionHandle, string, []ProcedureStep, uint64) ([]StepResult, error) {
+	return nil, errNoTransport
+}
+func (noTransport) ChangePassword(SessionHandle, string, string, uint64) error { return errNoTransport }
+func (noTransport) X25519SharedSecret(SessionHandle, string, string, []byte) ([32]byte, error) {
+	return [32]byte{}, errNoTransport
+}
+func (noTransport) GenerateSecp256k1KeyPair(SessionHandle, string, string) ([PublicKeySizeSecp256k1]byte, error) {
+	return [PublicKeySizeSecp256k1]byte{}, errNoTransport
+}
+func (noTransport) GetPublicKeySecp256k1(SessionHandle, string) ([PublicKeySizeSecp256k1]byte, error) {
+	return [PublicKeySizeSecp256k1]byte{}, errNoTransport
+}
+func (noTransport) SignSecp256k1ECDSA(SessionHandle, string, [32]byte) ([SignatureSizeSecp256k1]byte, error) {
+	return [SignatureSizeSecp256k1]byte{}, errNoTransport
+}
+func (noTransport) SignSecp256k1Schnorr(SessionHandle, string, []byte) ([64]byte, error) {
+	return [64]byte{}, errNoTransport
+}
+func (noTransport) Cancel(SessionHandle, uint64) {}
+func (noTransport) Destroy(SessionHandle)        {}
+
+func defaultTransport() Transport {
+	return noTransport{}
+}
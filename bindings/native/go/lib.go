@@ -3,16 +3,37 @@
 package stronghold
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/awnumar/memguard"
 	"golang.org/x/crypto/blake2b"
 	"os"
+	"strings"
+	"sync"
 )
 
+// StrongholdNative is safe for concurrent use. mu guards handle: callers
+// take RLock for the duration of an operation and Close takes Lock, so
+// Close blocks until every in-flight operation has returned instead of
+// yanking handle out from underneath them. A *Context call that is
+// cancelled returns as soon as ctx is done, without waiting for its
+// transport call to actually finish, so mu alone is not enough to keep
+// Close from racing that now-orphaned goroutine; inFlight tracks every
+// goroutine spawned by runContext so Close can wait for it to actually
+// return before destroying handle. recordLocks serializes calls against
+// the same recordPath (e.g. two concurrent Sign calls for the same key)
+// without serializing calls against different ones.
 type StrongholdNative struct {
-	ptr     StrongholdPointer
-	enclave *memguard.Enclave
+	mu                  sync.RWMutex
+	handle              SessionHandle
+	transport           Transport
+	enclave             *memguard.Enclave
+	allowMnemonicExport bool
+	snapshotPath        string
+	inFlight            sync.WaitGroup
+	recordLocksMu       sync.Mutex
+	recordLocks         map[string]*recordLockEntry
 }
 
 func zeroKeyBuffer(data *[]byte) {
@@ -22,26 +43,42 @@ func zeroKeyBuffer(data *[]byte) {
 }
 
 // NewStronghold will safely clear the provided key and make it unusable after this call.
-func NewStronghold(key []byte) *StrongholdNative {
-	stronghold := NewStrongholdUnsafe(key)
+func NewStronghold(key []byte, opts ...StrongholdOption) *StrongholdNative {
+	stronghold := NewStrongholdUnsafe(key, opts...)
 	zeroKeyBuffer(&key)
 	return stronghold
 }
 
 // NewStrongholdUnsafe creates a Stronghold instance without clearing the provided key.
 // This might leave the provided key inside readable memory space.
-func NewStrongholdUnsafe(key []byte) *StrongholdNative {
-	stronghold := &StrongholdNative{}
+func NewStrongholdUnsafe(key []byte, opts ...StrongholdOption) *StrongholdNative {
+	stronghold := newStrongholdWithOptions(opts)
 	stronghold.enclave = memguard.NewEnclave(key)
 	return stronghold
 }
 
-func NewStrongholdWithEnclave(enclave *memguard.Enclave) *StrongholdNative {
-	stronghold := &StrongholdNative{}
+func NewStrongholdWithEnclave(enclave *memguard.Enclave, opts ...StrongholdOption) *StrongholdNative {
+	stronghold := newStrongholdWithOptions(opts)
 	stronghold.enclave = enclave
 	return stronghold
 }
 
+// newStrongholdWithOptions wires up the default transport (cgoTransport in
+// a cgo build, noTransport otherwise), then lets
+// opts (e.g. WithTransport) override it before the caller's enclave is set.
+func newStrongholdWithOptions(opts []StrongholdOption) *StrongholdNative {
+	stronghold := &StrongholdNative{
+		transport:   defaultTransport(),
+		recordLocks: make(map[string]*recordLockEntry),
+	}
+
+	for _, opt := range opts {
+		opt(stronghold)
+	}
+
+	return stronghold
+}
+
 type LogLevel int
 
 const (
@@ -57,8 +94,19 @@ func SetLogLevel(level LogLevel) {
 	setLogLevel(int(level))
 }
 
+// SetMnemonicExportPolicy controls whether ExportMnemonic is allowed to
+// hand a recovery phrase back to the caller. It defaults to false: export
+// must be opted into explicitly, since it is the one call that surfaces
+// seed material outside of the enclave.
+func (s *StrongholdNative) SetMnemonicExportPolicy(allowed bool) {
+	s.allowMnemonicExport = allowed
+}
+
+// validate reports customErrorMessage if no snapshot is open. Callers must
+// hold s.mu (for read or write) before calling validate, since s.handle is
+// only safe to read under that lock.
 func (s *StrongholdNative) validate(customErrorMessage string) error {
-	if s.ptr == nil {
+	if s.handle == nil {
 		return errors.New(customErrorMessage)
 	}
 
@@ -66,15 +114,30 @@ func (s *StrongholdNative) validate(customErrorMessage string) error {
 }
 
 func (s *StrongholdNative) OpenOrCreate(snapshotPath string) (bool, error) {
+	return s.OpenOrCreateContext(context.Background(), snapshotPath)
+}
+
+// OpenOrCreateContext is OpenOrCreate with a context that aborts the
+// underlying Argon2id snapshot decryption/encryption if cancelled.
+func (s *StrongholdNative) OpenOrCreateContext(ctx context.Context, snapshotPath string) (bool, error) {
 	if _, err := os.Stat(snapshotPath); errors.Is(err, os.ErrNotExist) {
-		return s.Create(snapshotPath)
+		return s.CreateContext(ctx, snapshotPath)
 	}
 
-	return s.Open(snapshotPath)
+	return s.OpenContext(ctx, snapshotPath)
 }
 
 func (s *StrongholdNative) Open(snapshotPath string) (bool, error) {
-	if s.ptr != nil {
+	return s.OpenContext(context.Background(), snapshotPath)
+}
+
+// OpenContext is Open with a context that aborts the underlying Argon2id
+// snapshot decryption if cancelled before it completes.
+func (s *StrongholdNative) OpenContext(ctx context.Context, snapshotPath string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.handle != nil {
 		return false, errors.New("snapshot is already open")
 	}
 
@@ -85,17 +148,32 @@ func (s *StrongholdNative) Open(snapshotPath string) (bool, error) {
 		return false, err
 	}
 
-	s.ptr, err = loadSnapshot(snapshotPath, buffer.String())
+	token := newCancelToken()
+	handle, err := runContext(ctx, s, token, func() (SessionHandle, error) {
+		return s.transport.LoadSnapshot(snapshotPath, buffer.String(), token)
+	})
 
 	if err != nil {
 		return false, err
 	}
 
+	s.handle = handle
+	s.snapshotPath = snapshotPath
+
 	return true, nil
 }
 
 func (s *StrongholdNative) Create(snapshotPath string) (bool, error) {
-	if s.ptr != nil {
+	return s.CreateContext(context.Background(), snapshotPath)
+}
+
+// CreateContext is Create with a context that aborts the underlying
+// Argon2id snapshot encryption if cancelled before it completes.
+func (s *StrongholdNative) CreateContext(ctx context.Context, snapshotPath string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.handle != nil {
 		return false, errors.New("snapshot is already open")
 	}
 
@@ -106,27 +184,52 @@ func (s *StrongholdNative) Create(snapshotPath string) (bool, error) {
 		return false, err
 	}
 
-	s.ptr, err = createSnapshot(snapshotPath, buffer.String())
+	token := newCancelToken()
+	handle, err := runContext(ctx, s, token, func() (SessionHandle, error) {
+		return s.transport.CreateSnapshot(snapshotPath, buffer.String(), token)
+	})
 
 	if err != nil {
 		return false, err
 	}
 
+	s.handle = handle
+	s.snapshotPath = snapshotPath
+
 	return true, nil
 }
 
+// Close releases the snapshot. It takes the instance's write lock, so it
+// blocks until every operation already in flight (each holding a read
+// lock) has returned, rather than clearing handle out from underneath
+// them. A cancelled *Context call returns to its caller, and so releases
+// its read lock, before its transport call actually finishes; inFlight.Wait
+// closes that gap by blocking until every goroutine spawned by runContext -
+// including ones orphaned by cancellation - has stopped touching handle.
 func (s *StrongholdNative) Close() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if err := s.validate("instance is already closed"); err != nil {
 		return false, err
 	}
 
-	destroyStronghold(s.ptr)
-	s.ptr = nil
+	s.inFlight.Wait()
+
+	s.transport.Destroy(s.handle)
+	s.handle = nil
 
 	return true, nil
 }
 
 func (s *StrongholdNative) GenerateED25519KeyPair(recordPath string) ([PublicKeySize]byte, error) {
+	return s.GenerateED25519KeyPairContext(context.Background(), recordPath)
+}
+
+func (s *StrongholdNative) GenerateED25519KeyPairContext(ctx context.Context, recordPath string) ([PublicKeySize]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	if err := s.validate("stronghold is closed. Call open()"); err != nil {
 		return [PublicKeySize]byte{}, err
 	}
@@ -138,36 +241,77 @@ func (s *StrongholdNative) GenerateED25519KeyPair(recordPath string) ([PublicKey
 		return [PublicKeySize]byte{}, err
 	}
 
-	return generateED25519KeyPair(s.ptr, buffer.String(), recordPath)
+	unlock := s.lockRecord(recordPath)
+	defer unlock()
+
+	return runContext(ctx, s, newCancelToken(), func() ([PublicKeySize]byte, error) {
+		return s.transport.GenerateED25519KeyPair(s.handle, buffer.String(), recordPath)
+	})
 }
 
 func (s *StrongholdNative) Sign(recordPath string, data []byte) ([SignatureSize]byte, error) {
+	return s.SignContext(context.Background(), recordPath, data)
+}
+
+func (s *StrongholdNative) SignContext(ctx context.Context, recordPath string, data []byte) ([SignatureSize]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	if err := s.validate("stronghold is closed. Call open()"); err != nil {
 		return [SignatureSize]byte{}, err
 	}
 
-	return sign(s.ptr, recordPath, data)
+	unlock := s.lockRecord(recordPath)
+	defer unlock()
+
+	return runContext(ctx, s, newCancelToken(), func() ([SignatureSize]byte, error) {
+		return s.transport.Sign(s.handle, recordPath, data)
+	})
+}
+
+func (s *StrongholdNative) SignForDerived(index uint32, data []byte) ([SignatureSize]byte, error) {
+	return s.SignForDerivedContext(context.Background(), index, data)
 }
 
-func (s* StrongholdNative) SignForDerived(index uint32, data []byte) ([SignatureSize]byte, error) {
+func (s *StrongholdNative) SignForDerivedContext(ctx context.Context, index uint32, data []byte) ([SignatureSize]byte, error) {
 	recordPath := fmt.Sprintf("seed.%d", index)
-	return s.Sign(recordPath, data)
+	return s.SignContext(ctx, recordPath, data)
 }
 
 func (s *StrongholdNative) GetPublicKey(recordPath string) ([PublicKeySize]byte, error) {
+	return s.GetPublicKeyContext(context.Background(), recordPath)
+}
+
+func (s *StrongholdNative) GetPublicKeyContext(ctx context.Context, recordPath string) ([PublicKeySize]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	if err := s.validate("stronghold is closed. Call open()"); err != nil {
 		return [PublicKeySize]byte{}, err
 	}
 
-	return getPublicKey(s.ptr, recordPath)
+	return runContext(ctx, s, newCancelToken(), func() ([PublicKeySize]byte, error) {
+		return s.transport.GetPublicKey(s.handle, recordPath)
+	})
 }
 
 func (s *StrongholdNative) GetPublicKeyFromDerived(index uint32) ([PublicKeySize]byte, error) {
+	return s.GetPublicKeyFromDerivedContext(context.Background(), index)
+}
+
+func (s *StrongholdNative) GetPublicKeyFromDerivedContext(ctx context.Context, index uint32) ([PublicKeySize]byte, error) {
 	recordPath := fmt.Sprintf("seed.%d", index)
-	return getPublicKey(s.ptr, recordPath)
+	return s.GetPublicKeyContext(ctx, recordPath)
 }
 
 func (s *StrongholdNative) GenerateSeed() (bool, error) {
+	return s.GenerateSeedContext(context.Background())
+}
+
+func (s *StrongholdNative) GenerateSeedContext(ctx context.Context) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	if err := s.validate("stronghold is closed. Call open()"); err != nil {
 		return false, err
 	}
@@ -179,10 +323,19 @@ func (s *StrongholdNative) GenerateSeed() (bool, error) {
 		return false, err
 	}
 
-	return generateSeed(s.ptr, buffer.String())
+	return runContext(ctx, s, newCancelToken(), func() (bool, error) {
+		return s.transport.GenerateSeed(s.handle, buffer.String())
+	})
 }
 
 func (s *StrongholdNative) DeriveSeed(index uint32) (bool, error) {
+	return s.DeriveSeedContext(context.Background(), index)
+}
+
+func (s *StrongholdNative) DeriveSeedContext(ctx context.Context, index uint32) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	if err := s.validate("stronghold is closed. Call open()"); err != nil {
 		return false, err
 	}
@@ -194,15 +347,21 @@ func (s *StrongholdNative) DeriveSeed(index uint32) (bool, error) {
 		return false, err
 	}
 
-	return deriveSeed(s.ptr, buffer.String(), index)
+	return runContext(ctx, s, newCancelToken(), func() (bool, error) {
+		return s.transport.DeriveSeed(s.handle, buffer.String(), index)
+	})
 }
 
-func (s *StrongholdNative) GetAddress(index uint32) ([PublicKeySize]byte, error) {
-	if err := s.validate("stronghold is closed. Call open()"); err != nil {
-		return [PublicKeySize]byte{}, err
-	}
+// GetAddress derives the Blake2b-256 address hash for the public key stored
+// under recordPath. recordPath is typically produced by DerivePath.
+func (s *StrongholdNative) GetAddress(recordPath string) ([PublicKeySize]byte, error) {
+	return s.GetAddressContext(context.Background(), recordPath)
+}
 
-	publicKey, err := s.GetPublicKeyFromDerived(index)
+// GetAddressContext is GetAddress with a context that aborts the
+// underlying GetPublicKey call if cancelled.
+func (s *StrongholdNative) GetAddressContext(ctx context.Context, recordPath string) ([PublicKeySize]byte, error) {
+	publicKey, err := s.GetPublicKeyContext(ctx, recordPath)
 
 	if err != nil {
 		return [PublicKeySize]byte{}, err
@@ -212,3 +371,141 @@ func (s *StrongholdNative) GetAddress(index uint32) ([PublicKeySize]byte, error)
 
 	return addressHash, nil
 }
+
+// GenerateMnemonic generates entropyBits bits of entropy inside the Rust
+// core and returns the corresponding BIP-39 mnemonic. entropyBits must be
+// one of 128, 160, 192, 224 or 256.
+func (s *StrongholdNative) GenerateMnemonic(entropyBits int) (string, error) {
+	return s.GenerateMnemonicContext(context.Background(), entropyBits)
+}
+
+func (s *StrongholdNative) GenerateMnemonicContext(ctx context.Context, entropyBits int) (string, error) {
+	return runContext(ctx, s, newCancelToken(), func() (string, error) {
+		return s.transport.GenerateMnemonic(entropyBits)
+	})
+}
+
+// ImportMnemonic derives a seed from mnemonic and passphrase using
+// PBKDF2-HMAC-SHA512 ("mnemonic"+passphrase, 2048 rounds) and stores it
+// in the vault under recordPath. The seed never leaves the enclave.
+func (s *StrongholdNative) ImportMnemonic(mnemonic string, passphrase string, recordPath string) error {
+	return s.ImportMnemonicContext(context.Background(), mnemonic, passphrase, recordPath)
+}
+
+func (s *StrongholdNative) ImportMnemonicContext(ctx context.Context, mnemonic string, passphrase string, recordPath string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.validate("stronghold is closed. Call open()"); err != nil {
+		return err
+	}
+
+	buffer, err := s.enclave.Open()
+	defer buffer.Destroy()
+
+	if err != nil {
+		return err
+	}
+
+	unlock := s.lockRecord(recordPath)
+	defer unlock()
+
+	_, err = runContext(ctx, s, newCancelToken(), func() (struct{}, error) {
+		return struct{}{}, s.transport.ImportMnemonic(s.handle, buffer.String(), mnemonic, passphrase, recordPath)
+	})
+
+	return err
+}
+
+// ExportMnemonic returns the BIP-39 mnemonic backing recordPath. It is
+// refused unless SetMnemonicExportPolicy(true) has been called, since it
+// is the one call that surfaces seed material outside of the enclave.
+func (s *StrongholdNative) ExportMnemonic(recordPath string) (string, error) {
+	return s.ExportMnemonicContext(context.Background(), recordPath)
+}
+
+func (s *StrongholdNative) ExportMnemonicContext(ctx context.Context, recordPath string) (string, error) {
+	if !s.allowMnemonicExport {
+		return "", errors.New("mnemonic export is disabled, call SetMnemonicExportPolicy(true) to allow it")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.validate("stronghold is closed. Call open()"); err != nil {
+		return "", err
+	}
+
+	buffer, err := s.enclave.Open()
+	defer buffer.Destroy()
+
+	if err != nil {
+		return "", err
+	}
+
+	return runContext(ctx, s, newCancelToken(), func() (string, error) {
+		return s.transport.ExportMnemonic(s.handle, buffer.String(), recordPath)
+	})
+}
+
+// DerivePath derives a BIP-32/SLIP-10 style child key from the seed (or
+// key) stored under recordPath, following path. Each element of path is
+// a child index; set the high bit (e.g. index|0x80000000) to request
+// hardened derivation. The derived key is stored under, and its record
+// path returned as, childRecordPath - the private material is never
+// copied out to Go.
+func (s *StrongholdNative) DerivePath(recordPath string, path []uint32) (string, error) {
+	return s.DerivePathContext(context.Background(), recordPath, path)
+}
+
+func (s *StrongholdNative) DerivePathContext(ctx context.Context, recordPath string, path []uint32) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.validate("stronghold is closed. Call open()"); err != nil {
+		return "", err
+	}
+
+	buffer, err := s.enclave.Open()
+	defer buffer.Destroy()
+
+	if err != nil {
+		return "", err
+	}
+
+	childRecordPath := formatDerivationPath(recordPath, path)
+
+	unlock := s.lockRecord(recordPath)
+	defer unlock()
+
+	_, err = runContext(ctx, s, newCancelToken(), func() (struct{}, error) {
+		return struct{}{}, s.transport.DerivePath(s.handle, buffer.String(), recordPath, path, childRecordPath)
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return childRecordPath, nil
+}
+
+// formatDerivationPath renders path as a record path rooted at recordPath,
+// e.g. "m/44'/4218'/0'/0/0" for path []uint32{44 | hardenedBit, 4218 |
+// hardenedBit, hardenedBit, 0, 0}.
+func formatDerivationPath(recordPath string, path []uint32) string {
+	segments := make([]string, len(path))
+
+	for i, index := range path {
+		if index&hardenedBit != 0 {
+			segments[i] = fmt.Sprintf("%d'", index&^hardenedBit)
+		} else {
+			segments[i] = fmt.Sprintf("%d", index)
+		}
+	}
+
+	return recordPath + "/" + strings.Join(segments, "/")
+}
+
+// hardenedBit marks a BIP-32 child index as hardened, following the
+// standard high-bit convention.
+const hardenedBit = 1 << 31
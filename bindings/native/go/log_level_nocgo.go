@@ -0,0 +1,11 @@
+// Copyright 2020-2022 IOTA Stiftung
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !cgo
+
+package stronghold
+
+// setLogLevel is a no-op in a CGO_ENABLED=0 build: there is no in-process
+// native core to configure, and an IPC-backed Transport controls its own
+// sidecar's log level independently of this process.
+func setLogLevel(level int) {}
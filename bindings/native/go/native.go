@@ -1,3 +1,5 @@
+//go:build cgo
+
 package stronghold
 
 import (
@@ -6,6 +8,33 @@ import (
 	"unsafe"
 )
 
+// Native symbol dependencies
+//
+// dist/stronghold_native.h is generated by the stronghold_native Rust
+// crate's build and is not part of this checkout, so the symbols below
+// are an explicit, tracked dependency rather than something this package
+// can implement on its own: each one must land in that crate (and its
+// cbindgen header) before a cgo build of this package will link.
+//
+//   - stronghold_generate_mnemonic, stronghold_import_mnemonic,
+//     stronghold_export_mnemonic, stronghold_derive_path,
+//     stronghold_destroy_string: BIP-39 mnemonic import/export and
+//     BIP-32/BIP-44 path derivation.
+//   - stronghold_execute_procedure: runs a CBOR-encoded []ProcedureStep
+//     batch and returns a CBOR-encoded []StepResult (see procedures.go
+//     and wire.go); the step/result schemas are shared with the Rust
+//     side and must stay in lockstep with it.
+//   - stronghold_change_password, stronghold_x25519_diffie_hellman:
+//     snapshot password rotation and the X25519 shared secret used to
+//     decrypt a portable snapshot export (see snapshot_portability.go).
+//   - stronghold_generate_secp256k1_keypair,
+//     stronghold_get_public_key_secp256k1, stronghold_sign_secp256k1_ecdsa,
+//     stronghold_sign_secp256k1_schnorr: secp256k1 key generation and
+//     ECDSA/BIP-340 Schnorr signing alongside Ed25519 (see secp256k1.go).
+//   - stronghold_cancel, plus a trailing cancel-token argument on
+//     stronghold_create and stronghold_load: aborting an in-flight
+//     operation by its token (see concurrency.go).
+//
 // TODO: Clean up paths once we have a working build pipeline
 /*
 #cgo LDFLAGS: -Wl,-rpath,${SRCDIR}/../../../target/debug/ -L${SRCDIR}/../../../target/debug/ -L${SRCDIR}/dist/ -L${SRCDIR}/../dist/ -lstronghold_native
@@ -18,14 +47,17 @@ type StrongholdPointer *C.struct_StrongholdWrapper
 const SignatureSize = 64
 const PublicKeySize = 32
 
-func createSnapshot(snapshotPath string, key string) (StrongholdPointer, error) {
+const SignatureSizeSecp256k1 = 65
+const PublicKeySizeSecp256k1 = 33
+
+func createSnapshot(snapshotPath string, key string, cancelToken uint64) (StrongholdPointer, error) {
 	snapshotPathNative := C.CString(snapshotPath)
 	defer C.free(unsafe.Pointer(snapshotPathNative))
 
 	keyNative := C.CString(key)
 	defer C.free(unsafe.Pointer(keyNative))
 
-	ptr := unsafe.Pointer(C.stronghold_create(snapshotPathNative, keyNative))
+	ptr := unsafe.Pointer(C.stronghold_create(snapshotPathNative, keyNative, C.uint64_t(cancelToken)))
 
 	if err := handlePtrError(ptr != nil); err != nil {
 		return nil, err
@@ -34,14 +66,14 @@ func createSnapshot(snapshotPath string, key string) (StrongholdPointer, error)
 	return StrongholdPointer(ptr), nil
 }
 
-func loadSnapshot(snapshotPath string, key string) (StrongholdPointer, error) {
+func loadSnapshot(snapshotPath string, key string, cancelToken uint64) (StrongholdPointer, error) {
 	snapshotPathNative := C.CString(snapshotPath)
 	defer C.free(unsafe.Pointer(snapshotPathNative))
 
 	keyNative := C.CString(key)
 	defer C.free(unsafe.Pointer(keyNative))
 
-	ptr := unsafe.Pointer(C.stronghold_load(snapshotPathNative, keyNative))
+	ptr := unsafe.Pointer(C.stronghold_load(snapshotPathNative, keyNative, C.uint64_t(cancelToken)))
 
 	if err := handlePtrError(ptr != nil); err != nil {
 		return nil, err
@@ -54,10 +86,28 @@ func destroyStronghold(strongholdPtr StrongholdPointer) {
 	C.stronghold_destroy_stronghold(strongholdPtr)
 }
 
+// cancel asks the native core to abort the operation tagged with
+// cancelToken (e.g. an in-flight Argon2id snapshot decryption). strongholdPtr
+// may be nil when cancelling a create/load call that has not produced a
+// pointer yet.
+func cancel(strongholdPtr StrongholdPointer, cancelToken uint64) {
+	C.stronghold_cancel(strongholdPtr, C.uint64_t(cancelToken))
+}
+
 func destroyErrorPointer(ptr *C.char) {
 	C.stronghold_destroy_error(ptr)
 }
 
+// destroyStringPointer frees a plain C string returned by a data-producing
+// call (e.g. stronghold_generate_mnemonic), as opposed to destroyErrorPointer
+// which frees the dedicated error-message allocation from
+// stronghold_get_last_error. The two must not be used interchangeably even
+// though both wrap a *C.char, since each is freed with the allocator that
+// produced it.
+func destroyStringPointer(ptr *C.char) {
+	C.stronghold_destroy_string(ptr)
+}
+
 func destroyDataPointer(ptr unsafe.Pointer) {
 	C.stronghold_destroy_data_pointer((*C.uchar)(ptr)) //nolint:typecheck
 }
@@ -155,6 +205,239 @@ func deriveSeed(strongholdPtr StrongholdPointer, key string, index uint32) (bool
 	return true, nil
 }
 
+func generateMnemonic(entropyBits int) (string, error) {
+	entropyBitsNative := C.size_t(entropyBits)
+
+	mnemonicPointer := C.stronghold_generate_mnemonic(entropyBitsNative)
+
+	if err := handlePtrError(mnemonicPointer != nil); err != nil {
+		return "", err
+	}
+
+	mnemonic := C.GoString(mnemonicPointer)
+	destroyStringPointer(mnemonicPointer)
+
+	return mnemonic, nil
+}
+
+func importMnemonic(strongholdPtr StrongholdPointer, key string, mnemonic string, passphrase string, recordPath string) (bool, error) {
+	keyNative := C.CString(key)
+	defer C.free(unsafe.Pointer(keyNative))
+
+	mnemonicNative := C.CString(mnemonic)
+	defer C.free(unsafe.Pointer(mnemonicNative))
+
+	passphraseNative := C.CString(passphrase)
+	defer C.free(unsafe.Pointer(passphraseNative))
+
+	recordPathNative := C.CString(recordPath)
+	defer C.free(unsafe.Pointer(recordPathNative))
+
+	imported := bool(C.stronghold_import_mnemonic(strongholdPtr, keyNative, mnemonicNative, passphraseNative, recordPathNative))
+
+	if err := handlePtrError(imported); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func exportMnemonic(strongholdPtr StrongholdPointer, key string, recordPath string) (string, error) {
+	keyNative := C.CString(key)
+	defer C.free(unsafe.Pointer(keyNative))
+
+	recordPathNative := C.CString(recordPath)
+	defer C.free(unsafe.Pointer(recordPathNative))
+
+	mnemonicPointer := C.stronghold_export_mnemonic(strongholdPtr, keyNative, recordPathNative)
+
+	if err := handlePtrError(mnemonicPointer != nil); err != nil {
+		return "", err
+	}
+
+	mnemonic := C.GoString(mnemonicPointer)
+	destroyStringPointer(mnemonicPointer)
+
+	return mnemonic, nil
+}
+
+func derivePath(strongholdPtr StrongholdPointer, key string, recordPath string, path []uint32, childRecordPath string) (bool, error) {
+	keyNative := C.CString(key)
+	defer C.free(unsafe.Pointer(keyNative))
+
+	recordPathNative := C.CString(recordPath)
+	defer C.free(unsafe.Pointer(recordPathNative))
+
+	childRecordPathNative := C.CString(childRecordPath)
+	defer C.free(unsafe.Pointer(childRecordPathNative))
+
+	var pathNative *C.uint
+	if len(path) > 0 {
+		pathNative = (*C.uint)(unsafe.Pointer(&path[0]))
+	}
+	pathLength := C.size_t(len(path))
+
+	derived := bool(C.stronghold_derive_path(strongholdPtr, keyNative, recordPathNative, pathNative, pathLength, childRecordPathNative))
+
+	if err := handlePtrError(derived); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func executeProcedure(strongholdPtr StrongholdPointer, key string, requestData []byte, cancelToken uint64) ([]byte, error) {
+	keyNative := C.CString(key)
+	defer C.free(unsafe.Pointer(keyNative))
+
+	requestPtr := (*C.uchar)(unsafe.Pointer(&requestData[0]))
+	requestLength := C.size_t(len(requestData))
+
+	responsePointer := unsafe.Pointer(C.stronghold_execute_procedure(strongholdPtr, keyNative, requestPtr, requestLength, C.uint64_t(cancelToken)))
+
+	if err := handlePtrError(responsePointer != nil); err != nil {
+		return nil, err
+	}
+
+	responseData := *(*[]byte)(responsePointer)
+	responseDataCopy := make([]byte, len(responseData))
+	copy(responseDataCopy, responseData)
+
+	destroyDataPointer(responsePointer)
+
+	return responseDataCopy, nil
+}
+
+func changePassword(strongholdPtr StrongholdPointer, oldKey string, newKey string, cancelToken uint64) (bool, error) {
+	oldKeyNative := C.CString(oldKey)
+	defer C.free(unsafe.Pointer(oldKeyNative))
+
+	newKeyNative := C.CString(newKey)
+	defer C.free(unsafe.Pointer(newKeyNative))
+
+	changed := bool(C.stronghold_change_password(strongholdPtr, oldKeyNative, newKeyNative, C.uint64_t(cancelToken)))
+
+	if err := handlePtrError(changed); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func x25519DiffieHellman(strongholdPtr StrongholdPointer, key string, recordPath string, peerPublicKey []byte) ([32]byte, error) {
+	keyNative := C.CString(key)
+	defer C.free(unsafe.Pointer(keyNative))
+
+	recordPathNative := C.CString(recordPath)
+	defer C.free(unsafe.Pointer(recordPathNative))
+
+	peerPublicKeyPtr := (*C.uchar)(unsafe.Pointer(&peerPublicKey[0]))
+	peerPublicKeyLength := C.size_t(len(peerPublicKey))
+
+	sharedSecretPointer := unsafe.Pointer(C.stronghold_x25519_diffie_hellman(strongholdPtr, keyNative, recordPathNative, peerPublicKeyPtr, peerPublicKeyLength))
+
+	if err := handlePtrError(sharedSecretPointer != nil); err != nil {
+		return [32]byte{}, err
+	}
+
+	sharedSecretData := *(*[]byte)(sharedSecretPointer)
+
+	var sharedSecretCopy [32]byte
+	copy(sharedSecretCopy[:], sharedSecretData)
+
+	destroyDataPointer(sharedSecretPointer)
+
+	return sharedSecretCopy, nil
+}
+
+func generateSecp256k1KeyPair(strongholdPtr StrongholdPointer, key string, recordPath string) ([PublicKeySizeSecp256k1]byte, error) {
+	keyNative := C.CString(key)
+	defer C.free(unsafe.Pointer(keyNative))
+
+	recordPathNative := C.CString(recordPath)
+	defer C.free(unsafe.Pointer(recordPathNative))
+
+	publicKeyPointer := unsafe.Pointer(C.stronghold_generate_secp256k1_keypair(strongholdPtr, keyNative, recordPathNative))
+
+	if err := handlePtrError(publicKeyPointer != nil); err != nil {
+		return [PublicKeySizeSecp256k1]byte{}, err
+	}
+
+	publicKeyData := *(*[]byte)(publicKeyPointer)
+
+	var publicKeyCopy [PublicKeySizeSecp256k1]byte
+	copy(publicKeyCopy[:], publicKeyData)
+
+	destroyDataPointer(publicKeyPointer)
+
+	return publicKeyCopy, nil
+}
+
+func getPublicKeySecp256k1(strongholdPtr StrongholdPointer, recordPath string) ([PublicKeySizeSecp256k1]byte, error) {
+	recordPathNative := C.CString(recordPath)
+	defer C.free(unsafe.Pointer(recordPathNative))
+
+	publicKeyPointer := unsafe.Pointer(C.stronghold_get_public_key_secp256k1(strongholdPtr, recordPathNative))
+
+	if err := handlePtrError(publicKeyPointer != nil); err != nil {
+		return [PublicKeySizeSecp256k1]byte{}, err
+	}
+
+	publicKeyData := *(*[]byte)(publicKeyPointer)
+
+	var publicKeyCopy [PublicKeySizeSecp256k1]byte
+	copy(publicKeyCopy[:], publicKeyData)
+
+	destroyDataPointer(publicKeyPointer)
+
+	return publicKeyCopy, nil
+}
+
+func signSecp256k1ECDSA(strongholdPtr StrongholdPointer, recordPath string, digest [32]byte) ([SignatureSizeSecp256k1]byte, error) {
+	recordPathNative := C.CString(recordPath)
+	defer C.free(unsafe.Pointer(recordPathNative))
+
+	digestPtr := (*C.uchar)(unsafe.Pointer(&digest[0]))
+
+	signaturePointer := unsafe.Pointer(C.stronghold_sign_secp256k1_ecdsa(strongholdPtr, recordPathNative, digestPtr, C.size_t(len(digest))))
+
+	if err := handlePtrError(signaturePointer != nil); err != nil {
+		return [SignatureSizeSecp256k1]byte{}, err
+	}
+
+	signatureData := *(*[]byte)(signaturePointer)
+
+	var signatureCopy [SignatureSizeSecp256k1]byte
+	copy(signatureCopy[:], signatureData)
+
+	destroyDataPointer(signaturePointer)
+
+	return signatureCopy, nil
+}
+
+func signSecp256k1Schnorr(strongholdPtr StrongholdPointer, recordPath string, msg []byte) ([64]byte, error) {
+	recordPathNative := C.CString(recordPath)
+	defer C.free(unsafe.Pointer(recordPathNative))
+
+	msgPtr := (*C.uchar)(unsafe.Pointer(&msg[0]))
+	msgLength := C.size_t(len(msg))
+
+	signaturePointer := unsafe.Pointer(C.stronghold_sign_secp256k1_schnorr(strongholdPtr, recordPathNative, msgPtr, msgLength))
+
+	if err := handlePtrError(signaturePointer != nil); err != nil {
+		return [64]byte{}, err
+	}
+
+	signatureData := *(*[]byte)(signaturePointer)
+
+	var signatureCopy [64]byte
+	copy(signatureCopy[:], signatureData)
+
+	destroyDataPointer(signaturePointer)
+
+	return signatureCopy, nil
+}
+
 func handlePtrError(isValidResult bool) error {
 	if isValidResult {
 		return nil
@@ -0,0 +1,254 @@
+// Copyright 2020-2022 IOTA Stiftung
+// SPDX-License-Identifier: Apache-2.0
+package stronghold
+
+import "context"
+
+// HashAlgorithm selects the digest used by a Hash procedure step.
+type HashAlgorithm int
+
+const (
+	Blake2b256 HashAlgorithm = iota
+	Sha256
+	Sha512
+)
+
+// HMACAlgorithm selects the digest used by an Hmac procedure step.
+type HMACAlgorithm int
+
+const (
+	HMACSha256 HMACAlgorithm = iota
+	HMACSha512
+)
+
+// procedureStepKind identifies which primitive a ProcedureStep runs.
+type procedureStepKind int
+
+const (
+	stepSlip10Generate procedureStepKind = iota
+	stepSlip10Derive
+	stepEd25519PublicKey
+	stepEd25519Sign
+	stepSecp256k1Sign
+	stepX25519DiffieHellman
+	stepHkdf
+	stepPbkdf2
+	stepHmac
+	stepAesGcmEncrypt
+	stepAesGcmDecrypt
+	stepChaCha20Poly1305Encrypt
+	stepChaCha20Poly1305Decrypt
+	stepHash
+	stepMnemonicRecover
+)
+
+// noInputRef marks a ProcedureStep that does not consume a prior step's
+// output.
+const noInputRef = -1
+
+// ProcedureStep is one primitive in a Procedure. Steps that consume
+// another step's output (e.g. signing over a derived key) reference it by
+// InputRef, the index of that step in the procedure - the intermediate
+// value never crosses into Go.
+type ProcedureStep struct {
+	Kind            procedureStepKind
+	RecordPath      string
+	ChildRecordPath string
+	InputRef        int
+	Data            []byte
+	HashAlgorithm   HashAlgorithm
+	HMACAlgorithm   HMACAlgorithm
+	Mnemonic        string
+	Passphrase      string
+	Nonce           []byte
+	AAD             []byte
+	Salt            []byte
+	Info            []byte
+}
+
+// StepResult carries the output of a single ProcedureStep that Execute is
+// allowed to surface to Go (public keys, signatures, ciphertext, digests -
+// never private key material).
+type StepResult struct {
+	PublicKey []byte
+	Signature []byte
+	Data      []byte
+}
+
+// Procedure batches primitives that compose into one client-side call, so
+// intermediates (derived keys, shared secrets, ...) never leave the
+// enclave between steps. Build it with the step methods below and run it
+// with Execute.
+type Procedure struct {
+	stronghold *StrongholdNative
+	steps      []ProcedureStep
+}
+
+// NewProcedure starts a new, empty Procedure against this Stronghold
+// instance.
+func (s *StrongholdNative) NewProcedure() *Procedure {
+	return &Procedure{stronghold: s}
+}
+
+func (p *Procedure) append(step ProcedureStep) *Procedure {
+	p.steps = append(p.steps, step)
+	return p
+}
+
+// Slip10Generate generates a SLIP-10 seed and stores it under recordPath.
+func (p *Procedure) Slip10Generate(recordPath string) *Procedure {
+	return p.append(ProcedureStep{Kind: stepSlip10Generate, RecordPath: recordPath, InputRef: noInputRef})
+}
+
+// Slip10Derive derives childRecordPath's key from recordPath following the
+// BIP-32/SLIP-10 hardened-index convention.
+func (p *Procedure) Slip10Derive(recordPath string, childRecordPath string) *Procedure {
+	return p.append(ProcedureStep{Kind: stepSlip10Derive, RecordPath: recordPath, ChildRecordPath: childRecordPath, InputRef: noInputRef})
+}
+
+// Ed25519PublicKey fetches the Ed25519 public key for recordPath.
+func (p *Procedure) Ed25519PublicKey(recordPath string) *Procedure {
+	return p.append(ProcedureStep{Kind: stepEd25519PublicKey, RecordPath: recordPath, InputRef: noInputRef})
+}
+
+// Ed25519Sign signs data with the Ed25519 key at recordPath.
+func (p *Procedure) Ed25519Sign(recordPath string, data []byte) *Procedure {
+	return p.append(ProcedureStep{Kind: stepEd25519Sign, RecordPath: recordPath, Data: data, InputRef: noInputRef})
+}
+
+// Ed25519SignFromStep signs the output of the step at inputRef with the
+// Ed25519 key at recordPath, so the signed value never crosses into Go.
+func (p *Procedure) Ed25519SignFromStep(recordPath string, inputRef int) *Procedure {
+	return p.append(ProcedureStep{Kind: stepEd25519Sign, RecordPath: recordPath, InputRef: inputRef})
+}
+
+// Secp256k1Sign signs data with the secp256k1 key at recordPath.
+func (p *Procedure) Secp256k1Sign(recordPath string, data []byte) *Procedure {
+	return p.append(ProcedureStep{Kind: stepSecp256k1Sign, RecordPath: recordPath, Data: data, InputRef: noInputRef})
+}
+
+// Secp256k1SignFromStep signs the output of the step at inputRef with the
+// secp256k1 key at recordPath, so the signed value never crosses into Go.
+func (p *Procedure) Secp256k1SignFromStep(recordPath string, inputRef int) *Procedure {
+	return p.append(ProcedureStep{Kind: stepSecp256k1Sign, RecordPath: recordPath, InputRef: inputRef})
+}
+
+// X25519DiffieHellman computes the shared secret between recordPath's
+// X25519 key and publicKey, storing it under childRecordPath.
+func (p *Procedure) X25519DiffieHellman(recordPath string, publicKey []byte, childRecordPath string) *Procedure {
+	return p.append(ProcedureStep{Kind: stepX25519DiffieHellman, RecordPath: recordPath, Data: publicKey, ChildRecordPath: childRecordPath, InputRef: noInputRef})
+}
+
+// Hkdf runs HKDF-SHA512 over the key at recordPath with the given salt and
+// info, storing the output key material under childRecordPath.
+func (p *Procedure) Hkdf(recordPath string, salt []byte, info []byte, childRecordPath string) *Procedure {
+	return p.append(ProcedureStep{Kind: stepHkdf, RecordPath: recordPath, Salt: salt, Info: info, ChildRecordPath: childRecordPath, InputRef: noInputRef})
+}
+
+// Pbkdf2 runs PBKDF2-HMAC-SHA512 over passphrase with salt, storing the
+// derived key under childRecordPath.
+func (p *Procedure) Pbkdf2(passphrase string, salt []byte, childRecordPath string) *Procedure {
+	return p.append(ProcedureStep{Kind: stepPbkdf2, Passphrase: passphrase, Salt: salt, ChildRecordPath: childRecordPath, InputRef: noInputRef})
+}
+
+// Hmac computes an HMAC over data with the key at recordPath.
+func (p *Procedure) Hmac(algorithm HMACAlgorithm, recordPath string, data []byte) *Procedure {
+	return p.append(ProcedureStep{Kind: stepHmac, HMACAlgorithm: algorithm, RecordPath: recordPath, Data: data, InputRef: noInputRef})
+}
+
+// HmacFromStep computes an HMAC over the output of the step at inputRef
+// with the key at recordPath, so the hashed value never crosses into Go.
+func (p *Procedure) HmacFromStep(algorithm HMACAlgorithm, recordPath string, inputRef int) *Procedure {
+	return p.append(ProcedureStep{Kind: stepHmac, HMACAlgorithm: algorithm, RecordPath: recordPath, InputRef: inputRef})
+}
+
+// AesGcmEncrypt encrypts data with the key at recordPath under AES-GCM.
+func (p *Procedure) AesGcmEncrypt(recordPath string, nonce []byte, aad []byte, data []byte) *Procedure {
+	return p.append(ProcedureStep{Kind: stepAesGcmEncrypt, RecordPath: recordPath, Nonce: nonce, AAD: aad, Data: data, InputRef: noInputRef})
+}
+
+// AesGcmEncryptFromStep encrypts the output of the step at inputRef with
+// the key at recordPath under AES-GCM, so the plaintext never crosses
+// into Go.
+func (p *Procedure) AesGcmEncryptFromStep(recordPath string, nonce []byte, aad []byte, inputRef int) *Procedure {
+	return p.append(ProcedureStep{Kind: stepAesGcmEncrypt, RecordPath: recordPath, Nonce: nonce, AAD: aad, InputRef: inputRef})
+}
+
+// AesGcmDecrypt decrypts data with the key at recordPath under AES-GCM.
+func (p *Procedure) AesGcmDecrypt(recordPath string, nonce []byte, aad []byte, data []byte) *Procedure {
+	return p.append(ProcedureStep{Kind: stepAesGcmDecrypt, RecordPath: recordPath, Nonce: nonce, AAD: aad, Data: data, InputRef: noInputRef})
+}
+
+// AesGcmDecryptFromStep decrypts the output of the step at inputRef with
+// the key at recordPath under AES-GCM, so the ciphertext never crosses
+// into Go.
+func (p *Procedure) AesGcmDecryptFromStep(recordPath string, nonce []byte, aad []byte, inputRef int) *Procedure {
+	return p.append(ProcedureStep{Kind: stepAesGcmDecrypt, RecordPath: recordPath, Nonce: nonce, AAD: aad, InputRef: inputRef})
+}
+
+// ChaCha20Poly1305Encrypt encrypts data with the key at recordPath.
+func (p *Procedure) ChaCha20Poly1305Encrypt(recordPath string, nonce []byte, aad []byte, data []byte) *Procedure {
+	return p.append(ProcedureStep{Kind: stepChaCha20Poly1305Encrypt, RecordPath: recordPath, Nonce: nonce, AAD: aad, Data: data, InputRef: noInputRef})
+}
+
+// ChaCha20Poly1305EncryptFromStep encrypts the output of the step at
+// inputRef with the key at recordPath, so the plaintext never crosses
+// into Go.
+func (p *Procedure) ChaCha20Poly1305EncryptFromStep(recordPath string, nonce []byte, aad []byte, inputRef int) *Procedure {
+	return p.append(ProcedureStep{Kind: stepChaCha20Poly1305Encrypt, RecordPath: recordPath, Nonce: nonce, AAD: aad, InputRef: inputRef})
+}
+
+// ChaCha20Poly1305Decrypt decrypts data with the key at recordPath.
+func (p *Procedure) ChaCha20Poly1305Decrypt(recordPath string, nonce []byte, aad []byte, data []byte) *Procedure {
+	return p.append(ProcedureStep{Kind: stepChaCha20Poly1305Decrypt, RecordPath: recordPath, Nonce: nonce, AAD: aad, Data: data, InputRef: noInputRef})
+}
+
+// ChaCha20Poly1305DecryptFromStep decrypts the output of the step at
+// inputRef with the key at recordPath, so the ciphertext never crosses
+// into Go.
+func (p *Procedure) ChaCha20Poly1305DecryptFromStep(recordPath string, nonce []byte, aad []byte, inputRef int) *Procedure {
+	return p.append(ProcedureStep{Kind: stepChaCha20Poly1305Decrypt, RecordPath: recordPath, Nonce: nonce, AAD: aad, InputRef: inputRef})
+}
+
+// Hash hashes the output of the step at inputRef with algorithm.
+func (p *Procedure) Hash(algorithm HashAlgorithm, inputRef int) *Procedure {
+	return p.append(ProcedureStep{Kind: stepHash, HashAlgorithm: algorithm, InputRef: inputRef})
+}
+
+// MnemonicRecover recovers the seed for mnemonic+passphrase and stores it
+// under recordPath, mirroring ImportMnemonic but as a batchable step.
+func (p *Procedure) MnemonicRecover(mnemonic string, passphrase string, recordPath string) *Procedure {
+	return p.append(ProcedureStep{Kind: stepMnemonicRecover, Mnemonic: mnemonic, Passphrase: passphrase, RecordPath: recordPath, InputRef: noInputRef})
+}
+
+// Execute runs every step of the procedure as a single call into the
+// transport, returning one StepResult per step in order. No intermediate
+// key material is copied to Go between steps.
+func (p *Procedure) Execute() ([]StepResult, error) {
+	return p.ExecuteContext(context.Background())
+}
+
+// ExecuteContext is Execute with a context that aborts the batch if
+// cancelled before it completes.
+func (p *Procedure) ExecuteContext(ctx context.Context) ([]StepResult, error) {
+	s := p.stronghold
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.validate("stronghold is closed. Call open()"); err != nil {
+		return nil, err
+	}
+
+	buffer, err := s.enclave.Open()
+	defer buffer.Destroy()
+
+	if err != nil {
+		return nil, err
+	}
+
+	token := newCancelToken()
+	return runContext(ctx, s, token, func() ([]StepResult, error) {
+		return s.transport.ExecuteProcedure(s.handle, buffer.String(), p.steps, token)
+	})
+}
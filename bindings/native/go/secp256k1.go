@@ -0,0 +1,173 @@
+// Copyright 2020-2022 IOTA Stiftung
+// SPDX-License-Identifier: Apache-2.0
+package stronghold
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/crypto/sha3"
+	"math/big"
+)
+
+// GenerateSecp256k1KeyPair generates a secp256k1 key pair under recordPath
+// and returns its compressed public key.
+func (s *StrongholdNative) GenerateSecp256k1KeyPair(recordPath string) ([PublicKeySizeSecp256k1]byte, error) {
+	return s.GenerateSecp256k1KeyPairContext(context.Background(), recordPath)
+}
+
+func (s *StrongholdNative) GenerateSecp256k1KeyPairContext(ctx context.Context, recordPath string) ([PublicKeySizeSecp256k1]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.validate("stronghold is closed. Call open()"); err != nil {
+		return [PublicKeySizeSecp256k1]byte{}, err
+	}
+
+	buffer, err := s.enclave.Open()
+	defer buffer.Destroy()
+
+	if err != nil {
+		return [PublicKeySizeSecp256k1]byte{}, err
+	}
+
+	unlock := s.lockRecord(recordPath)
+	defer unlock()
+
+	return runContext(ctx, s, newCancelToken(), func() ([PublicKeySizeSecp256k1]byte, error) {
+		return s.transport.GenerateSecp256k1KeyPair(s.handle, buffer.String(), recordPath)
+	})
+}
+
+// GetPublicKeySecp256k1 returns the compressed secp256k1 public key stored
+// under recordPath.
+func (s *StrongholdNative) GetPublicKeySecp256k1(recordPath string) ([PublicKeySizeSecp256k1]byte, error) {
+	return s.GetPublicKeySecp256k1Context(context.Background(), recordPath)
+}
+
+func (s *StrongholdNative) GetPublicKeySecp256k1Context(ctx context.Context, recordPath string) ([PublicKeySizeSecp256k1]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.validate("stronghold is closed. Call open()"); err != nil {
+		return [PublicKeySizeSecp256k1]byte{}, err
+	}
+
+	return runContext(ctx, s, newCancelToken(), func() ([PublicKeySizeSecp256k1]byte, error) {
+		return s.transport.GetPublicKeySecp256k1(s.handle, recordPath)
+	})
+}
+
+// SignSecp256k1ECDSA signs digest with the secp256k1 key at recordPath,
+// returning a recoverable signature in r||s||v form.
+func (s *StrongholdNative) SignSecp256k1ECDSA(recordPath string, digest [32]byte) ([SignatureSizeSecp256k1]byte, error) {
+	return s.SignSecp256k1ECDSAContext(context.Background(), recordPath, digest)
+}
+
+func (s *StrongholdNative) SignSecp256k1ECDSAContext(ctx context.Context, recordPath string, digest [32]byte) ([SignatureSizeSecp256k1]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.validate("stronghold is closed. Call open()"); err != nil {
+		return [SignatureSizeSecp256k1]byte{}, err
+	}
+
+	unlock := s.lockRecord(recordPath)
+	defer unlock()
+
+	return runContext(ctx, s, newCancelToken(), func() ([SignatureSizeSecp256k1]byte, error) {
+		return s.transport.SignSecp256k1ECDSA(s.handle, recordPath, digest)
+	})
+}
+
+// SignSecp256k1Schnorr signs msg with the secp256k1 key at recordPath
+// following BIP-340.
+func (s *StrongholdNative) SignSecp256k1Schnorr(recordPath string, msg []byte) ([64]byte, error) {
+	return s.SignSecp256k1SchnorrContext(context.Background(), recordPath, msg)
+}
+
+func (s *StrongholdNative) SignSecp256k1SchnorrContext(ctx context.Context, recordPath string, msg []byte) ([64]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.validate("stronghold is closed. Call open()"); err != nil {
+		return [64]byte{}, err
+	}
+
+	unlock := s.lockRecord(recordPath)
+	defer unlock()
+
+	return runContext(ctx, s, newCancelToken(), func() ([64]byte, error) {
+		return s.transport.SignSecp256k1Schnorr(s.handle, recordPath, msg)
+	})
+}
+
+// EthereumAddress computes the Ethereum address for the secp256k1 key at
+// recordPath, i.e. keccak256(uncompressed_pubkey[1:])[12:].
+func (s *StrongholdNative) EthereumAddress(recordPath string) ([20]byte, error) {
+	return s.EthereumAddressContext(context.Background(), recordPath)
+}
+
+// EthereumAddressContext is EthereumAddress with a context that aborts the
+// underlying GetPublicKeySecp256k1 call if cancelled.
+func (s *StrongholdNative) EthereumAddressContext(ctx context.Context, recordPath string) ([20]byte, error) {
+	compressedPubKey, err := s.GetPublicKeySecp256k1Context(ctx, recordPath)
+	if err != nil {
+		return [20]byte{}, err
+	}
+
+	uncompressedPubKey, err := decompressSecp256k1PublicKey(compressedPubKey)
+	if err != nil {
+		return [20]byte{}, err
+	}
+
+	keccak256 := sha3.NewLegacyKeccak256()
+	keccak256.Write(uncompressedPubKey[1:])
+	hash := keccak256.Sum(nil)
+
+	var address [20]byte
+	copy(address[:], hash[12:])
+
+	return address, nil
+}
+
+// secp256k1FieldPrime is 2^256 - 2^32 - 977, the prime underlying the
+// secp256k1 curve y^2 = x^3 + 7.
+var secp256k1FieldPrime, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+
+// decompressSecp256k1PublicKey recovers the uncompressed (0x04 || X || Y)
+// encoding of a compressed (0x02/0x03 || X) secp256k1 public key by solving
+// y^2 = x^3 + 7 mod p and picking the root matching the compression prefix.
+// p ≡ 3 (mod 4), so the square root is x^((p+1)/4) mod p.
+func decompressSecp256k1PublicKey(compressed [PublicKeySizeSecp256k1]byte) ([65]byte, error) {
+	prefix := compressed[0]
+	if prefix != 0x02 && prefix != 0x03 {
+		return [65]byte{}, errors.New("stronghold: invalid secp256k1 compressed public key prefix")
+	}
+
+	x := new(big.Int).SetBytes(compressed[1:])
+
+	ySquared := new(big.Int).Exp(x, big.NewInt(3), secp256k1FieldPrime)
+	ySquared.Add(ySquared, big.NewInt(7))
+	ySquared.Mod(ySquared, secp256k1FieldPrime)
+
+	exponent := new(big.Int).Add(secp256k1FieldPrime, big.NewInt(1))
+	exponent.Rsh(exponent, 2)
+
+	y := new(big.Int).Exp(ySquared, exponent, secp256k1FieldPrime)
+
+	wantOdd := prefix == 0x03
+	if y.Bit(0) == 1 != wantOdd {
+		y.Sub(secp256k1FieldPrime, y)
+	}
+
+	var uncompressed [65]byte
+	uncompressed[0] = 0x04
+
+	xBytes := x.Bytes()
+	copy(uncompressed[1+32-len(xBytes):33], xBytes)
+
+	yBytes := y.Bytes()
+	copy(uncompressed[33+32-len(yBytes):65], yBytes)
+
+	return uncompressed, nil
+}
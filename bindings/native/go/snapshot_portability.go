@@ -0,0 +1,325 @@
+// Copyright 2020-2022 IOTA Stiftung
+// SPDX-License-Identifier: Apache-2.0
+package stronghold
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/awnumar/memguard"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"math/big"
+	"os"
+)
+
+// ChangePassword rotates the password protecting the currently open
+// snapshot. oldEnclave must hold the password the snapshot is presently
+// encrypted with; newEnclave holds the replacement. On success, s adopts
+// newEnclave and the old one is left for the caller to destroy.
+func (s *StrongholdNative) ChangePassword(oldEnclave *memguard.Enclave, newEnclave *memguard.Enclave) error {
+	return s.ChangePasswordContext(context.Background(), oldEnclave, newEnclave)
+}
+
+// ChangePasswordContext is ChangePassword with a context that aborts the
+// underlying Argon2id re-encryption if cancelled before it completes. It
+// takes the instance's write lock, rather than the read lock the key and
+// signing *Context methods take, because on success it replaces s.enclave - a
+// write that concurrent readers (every other method's s.enclave.Open())
+// must not race with.
+func (s *StrongholdNative) ChangePasswordContext(ctx context.Context, oldEnclave *memguard.Enclave, newEnclave *memguard.Enclave) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.validate("stronghold is closed. Call open()"); err != nil {
+		return err
+	}
+
+	oldBuffer, err := oldEnclave.Open()
+	if err != nil {
+		return err
+	}
+	defer oldBuffer.Destroy()
+
+	newBuffer, err := newEnclave.Open()
+	if err != nil {
+		return err
+	}
+	defer newBuffer.Destroy()
+
+	token := newCancelToken()
+	_, err = runContext(ctx, s, token, func() (struct{}, error) {
+		return struct{}{}, s.transport.ChangePassword(s.handle, oldBuffer.String(), newBuffer.String(), token)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	s.enclave = newEnclave
+
+	return nil
+}
+
+// snapshotEnvelopeMagic tags the export format so ImportSnapshot can refuse
+// anything else up front.
+var snapshotEnvelopeMagic = [4]byte{'S', 'H', 'E', '1'}
+
+// ExportSnapshot wraps the on-disk snapshot file in a portable authenticated
+// encryption envelope addressed to recipientPubKey (an Ed25519 public key,
+// converted internally to its X25519 birational equivalent), and writes it
+// to w. The envelope is: magic || ephemeral X25519 public key (32 bytes) ||
+// nonce (24 bytes) || ciphertext length (4 bytes, big-endian) ||
+// XChaCha20-Poly1305 ciphertext || Blake2b-256 frame checksum, following
+// the shape of NNCP's store-and-forward packets.
+func (s *StrongholdNative) ExportSnapshot(w io.Writer, recipientPubKey []byte) error {
+	return s.ExportSnapshotContext(context.Background(), w, recipientPubKey)
+}
+
+// ExportSnapshotContext is ExportSnapshot with a context: if ctx is already
+// done on entry it returns ctx.Err() without reading the snapshot. A
+// cancellation after that point does not interrupt the export.
+func (s *StrongholdNative) ExportSnapshotContext(ctx context.Context, w io.Writer, recipientPubKey []byte) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if s.snapshotPath == "" {
+		return errors.New("no snapshot is open, call Create or Open first")
+	}
+
+	plaintext, err := os.ReadFile(s.snapshotPath)
+	if err != nil {
+		return fmt.Errorf("stronghold: failed to read snapshot: %w", err)
+	}
+
+	recipientX25519PubKey, err := ed25519PublicKeyToX25519(recipientPubKey)
+	if err != nil {
+		return err
+	}
+
+	var ephemeralPrivKey [32]byte
+	if _, err := rand.Read(ephemeralPrivKey[:]); err != nil {
+		return fmt.Errorf("stronghold: failed to generate ephemeral key: %w", err)
+	}
+
+	ephemeralPubKey, err := curve25519.X25519(ephemeralPrivKey[:], curve25519.Basepoint)
+	if err != nil {
+		return fmt.Errorf("stronghold: failed to derive ephemeral public key: %w", err)
+	}
+
+	sharedSecret, err := curve25519.X25519(ephemeralPrivKey[:], recipientX25519PubKey[:])
+	if err != nil {
+		return fmt.Errorf("stronghold: failed to compute shared secret: %w", err)
+	}
+
+	aead, err := chacha20poly1305.NewX(deriveEnvelopeKey(sharedSecret))
+	if err != nil {
+		return fmt.Errorf("stronghold: failed to set up envelope cipher: %w", err)
+	}
+
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("stronghold: failed to generate nonce: %w", err)
+	}
+
+	ciphertext := aead.Seal(nil, nonce, plaintext, nil)
+
+	frame := make([]byte, 0, len(snapshotEnvelopeMagic)+len(ephemeralPubKey)+len(nonce)+4+len(ciphertext))
+	frame = append(frame, snapshotEnvelopeMagic[:]...)
+	frame = append(frame, ephemeralPubKey...)
+	frame = append(frame, nonce...)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(ciphertext)))
+	frame = append(frame, ciphertext...)
+
+	checksum := blake2b.Sum256(frame)
+	frame = append(frame, checksum[:]...)
+
+	if _, err := w.Write(frame); err != nil {
+		return fmt.Errorf("stronghold: failed to write envelope: %w", err)
+	}
+
+	return nil
+}
+
+// ImportSnapshot reads a portable envelope produced by ExportSnapshot from
+// r, decrypts it using the X25519 shared secret between the envelope's
+// ephemeral public key and the recipient's private key at
+// recipientPrivRecordPath (which must live in the currently open vault),
+// and atomically replaces the open snapshot's on-disk file with the
+// recovered content. The vault must be closed and reopened afterwards to
+// see the imported records.
+func (s *StrongholdNative) ImportSnapshot(r io.Reader, recipientPrivRecordPath string) error {
+	return s.ImportSnapshotContext(context.Background(), r, recipientPrivRecordPath)
+}
+
+// ImportSnapshotContext is ImportSnapshot with a context that aborts the
+// X25519 shared secret computation if cancelled before it completes.
+func (s *StrongholdNative) ImportSnapshotContext(ctx context.Context, r io.Reader, recipientPrivRecordPath string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.validate("stronghold is closed. Call open()"); err != nil {
+		return err
+	}
+
+	if s.snapshotPath == "" {
+		return errors.New("no snapshot is open, call Create or Open first")
+	}
+
+	frame, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("stronghold: failed to read envelope: %w", err)
+	}
+
+	minLength := len(snapshotEnvelopeMagic) + 32 + chacha20poly1305.NonceSizeX + 4 + chacha20poly1305.Overhead + blake2b.Size256
+	if len(frame) < minLength {
+		return errors.New("stronghold: envelope is too short")
+	}
+
+	checksum := frame[len(frame)-blake2b.Size256:]
+	body := frame[:len(frame)-blake2b.Size256]
+
+	expectedChecksum := blake2b.Sum256(body)
+	if !bytesEqual(checksum, expectedChecksum[:]) {
+		return errors.New("stronghold: envelope checksum mismatch")
+	}
+
+	offset := 0
+	magic := body[offset : offset+len(snapshotEnvelopeMagic)]
+	offset += len(snapshotEnvelopeMagic)
+
+	if !bytesEqual(magic, snapshotEnvelopeMagic[:]) {
+		return errors.New("stronghold: unrecognised envelope format")
+	}
+
+	ephemeralPubKey := body[offset : offset+32]
+	offset += 32
+
+	nonce := body[offset : offset+chacha20poly1305.NonceSizeX]
+	offset += chacha20poly1305.NonceSizeX
+
+	ciphertextLength := binary.BigEndian.Uint32(body[offset : offset+4])
+	offset += 4
+
+	if int(ciphertextLength) != len(body)-offset {
+		return errors.New("stronghold: envelope ciphertext length does not match frame size")
+	}
+
+	ciphertext := body[offset : offset+int(ciphertextLength)]
+
+	buffer, err := s.enclave.Open()
+	if err != nil {
+		return err
+	}
+	defer buffer.Destroy()
+
+	sharedSecret, err := runContext(ctx, s, newCancelToken(), func() ([32]byte, error) {
+		return s.transport.X25519SharedSecret(s.handle, buffer.String(), recipientPrivRecordPath, ephemeralPubKey)
+	})
+	if err != nil {
+		return err
+	}
+
+	aead, err := chacha20poly1305.NewX(deriveEnvelopeKey(sharedSecret[:]))
+	if err != nil {
+		return fmt.Errorf("stronghold: failed to set up envelope cipher: %w", err)
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return fmt.Errorf("stronghold: failed to decrypt envelope: %w", err)
+	}
+
+	tempPath := s.snapshotPath + ".import"
+	if err := os.WriteFile(tempPath, plaintext, 0600); err != nil {
+		return fmt.Errorf("stronghold: failed to write imported snapshot: %w", err)
+	}
+
+	if err := os.Rename(tempPath, s.snapshotPath); err != nil {
+		return fmt.Errorf("stronghold: failed to replace snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// deriveEnvelopeKey expands an X25519 shared secret into a 32-byte
+// XChaCha20-Poly1305 key with HKDF-SHA512.
+func deriveEnvelopeKey(sharedSecret []byte) []byte {
+	key := make([]byte, chacha20poly1305.KeySize)
+	_, _ = io.ReadFull(hkdf.New(sha512.New, sharedSecret, nil, []byte("stronghold-snapshot-export")), key)
+
+	return key
+}
+
+func bytesEqual(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ed25519FieldPrime is 2^255 - 19, the prime underlying Curve25519 and
+// Ed25519's field arithmetic.
+var ed25519FieldPrime = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+// ed25519PublicKeyToX25519 converts an Ed25519 public key to its Curve25519
+// (Montgomery) equivalent via the standard birational map
+// u = (1+y)/(1-y) mod p, matching libsodium's
+// crypto_sign_ed25519_pk_to_curve25519.
+func ed25519PublicKeyToX25519(edPublicKey []byte) ([32]byte, error) {
+	if len(edPublicKey) != 32 {
+		return [32]byte{}, errors.New("stronghold: an Ed25519 public key must be 32 bytes")
+	}
+
+	yBytes := make([]byte, 32)
+	copy(yBytes, edPublicKey)
+	yBytes[31] &^= 0x80 // clear the sign bit, which does not belong to y
+
+	y := new(big.Int).SetBytes(reverseBytes(yBytes))
+
+	one := big.NewInt(1)
+	numerator := new(big.Int).Mod(new(big.Int).Add(one, y), ed25519FieldPrime)
+	denominator := new(big.Int).Mod(new(big.Int).Sub(one, y), ed25519FieldPrime)
+	denominatorInverse := new(big.Int).ModInverse(denominator, ed25519FieldPrime)
+
+	if denominatorInverse == nil {
+		return [32]byte{}, errors.New("stronghold: public key has no corresponding Curve25519 point")
+	}
+
+	u := new(big.Int).Mod(new(big.Int).Mul(numerator, denominatorInverse), ed25519FieldPrime)
+
+	var result [32]byte
+	uBytes := u.Bytes()
+	copy(result[32-len(uBytes):], uBytes)
+
+	var littleEndianResult [32]byte
+	copy(littleEndianResult[:], reverseBytes(result[:]))
+
+	return littleEndianResult, nil
+}
+
+func reverseBytes(data []byte) []byte {
+	reversed := make([]byte, len(data))
+	for i, b := range data {
+		reversed[len(data)-1-i] = b
+	}
+
+	return reversed
+}
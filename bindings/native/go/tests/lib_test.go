@@ -3,14 +3,19 @@
 package tests
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"github.com/awnumar/memguard"
 	"hash/fnv"
 	"math/big"
 	"math/rand"
 	"os"
 	"path"
 	stronghold_go "stronghold"
+	"sync"
 	"testing"
+	"time"
 )
 
 const testPassword = "qawsedrf"
@@ -154,13 +159,192 @@ func TestGetPublicKeyFromDerivedSeed(t *testing.T) {
 	}
 }
 
+func TestGetAddressForKeyPair(t *testing.T) {
+	stronghold, dbPath := initializeStrongholdTest(t, true)
+	stronghold.Create(dbPath)
+
+	stronghold.GenerateED25519KeyPair("test")
+	address, err := stronghold.GetAddress("test")
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Log(address)
+}
+
 func TestGetAddressFromDerivedSeed(t *testing.T) {
 	stronghold, dbPath := initializeStrongholdTest(t, true)
 	stronghold.Create(dbPath)
 
 	stronghold.GenerateSeed()
 	stronghold.DeriveSeed(1)
-	address, err := stronghold.GetAddress(1)
+	address, err := stronghold.GetAddress(fmt.Sprintf("seed.%d", 1))
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Log(address)
+}
+
+func TestGenerateMnemonic(t *testing.T) {
+	stronghold, dbPath := initializeStrongholdTest(t, true)
+	stronghold.Create(dbPath)
+
+	mnemonic, err := stronghold.GenerateMnemonic(256)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	if mnemonic == "" {
+		t.Error("Expected a non-empty mnemonic")
+	}
+}
+
+func TestImportMnemonicAndDerivePath(t *testing.T) {
+	stronghold, dbPath := initializeStrongholdTest(t, true)
+	stronghold.Create(dbPath)
+
+	mnemonic, err := stronghold.GenerateMnemonic(256)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err := stronghold.ImportMnemonic(mnemonic, "", "m"); err != nil {
+		t.Error(err)
+	}
+
+	hardened := uint32(1) << 31
+	childRecordPath, err := stronghold.DerivePath("m", []uint32{44 | hardened, 4218 | hardened, 0 | hardened, 0, 0})
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	if childRecordPath != "m/44'/4218'/0'/0/0" {
+		t.Errorf("Unexpected child record path: %v", childRecordPath)
+	}
+}
+
+func TestExportMnemonicRequiresPolicy(t *testing.T) {
+	stronghold, dbPath := initializeStrongholdTest(t, true)
+	stronghold.Create(dbPath)
+
+	mnemonic, _ := stronghold.GenerateMnemonic(256)
+	stronghold.ImportMnemonic(mnemonic, "", "m")
+
+	if _, err := stronghold.ExportMnemonic("m"); err == nil {
+		t.Error("Expected export to be refused without an explicit policy")
+	}
+
+	stronghold.SetMnemonicExportPolicy(true)
+
+	if _, err := stronghold.ExportMnemonic("m"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestProcedureSlip10SignInOneCall(t *testing.T) {
+	stronghold, dbPath := initializeStrongholdTest(t, true)
+	stronghold.Create(dbPath)
+
+	results, err := stronghold.NewProcedure().
+		Slip10Generate("m").
+		Slip10Derive("m", "m/44'/4218'/0'").
+		Ed25519PublicKey("m/44'/4218'/0'").
+		Ed25519Sign("m/44'/4218'/0'", make([]byte, 32)).
+		Execute()
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(results) != 4 {
+		t.Errorf("Expected 4 step results, got %d", len(results))
+	}
+}
+
+func TestProcedureSignsHashOfPriorStepOutput(t *testing.T) {
+	stronghold, dbPath := initializeStrongholdTest(t, true)
+	stronghold.Create(dbPath)
+
+	results, err := stronghold.NewProcedure().
+		Slip10Generate("m").
+		Slip10Derive("m", "m/44'/4218'/0'").
+		Ed25519PublicKey("m/44'/4218'/0'").
+		Hash(stronghold_go.Blake2b256, 2).
+		Ed25519SignFromStep("m/44'/4218'/0'", 3).
+		Execute()
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(results) != 5 {
+		t.Errorf("Expected 5 step results, got %d", len(results))
+	}
+}
+
+func TestExportAndImportSnapshot(t *testing.T) {
+	sender, senderPath := initializeStrongholdTest(t, true)
+	sender.Create(senderPath)
+
+	recipient, recipientPath := initializeStrongholdTest(t, true)
+	recipient.Create(recipientPath)
+
+	recipientPubKey, err := recipient.GenerateED25519KeyPair("identity")
+	if err != nil {
+		t.Error(err)
+	}
+
+	var envelope bytes.Buffer
+	if err := sender.ExportSnapshot(&envelope, recipientPubKey[:]); err != nil {
+		t.Error(err)
+	}
+
+	if err := recipient.ImportSnapshot(&envelope, "identity"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	stronghold, dbPath := initializeStrongholdTest(t, true)
+	stronghold.Create(dbPath)
+
+	oldEnclave := memguard.NewEnclave([]byte(testPassword))
+	newEnclave := memguard.NewEnclave([]byte("s0m3thingNew"))
+
+	if err := stronghold.ChangePassword(oldEnclave, newEnclave); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSecp256k1KeyGenerationAndSigning(t *testing.T) {
+	stronghold, dbPath := initializeStrongholdTest(t, true)
+	stronghold.Create(dbPath)
+
+	_, err := stronghold.GenerateSecp256k1KeyPair("eth")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, err := stronghold.SignSecp256k1ECDSA("eth", [32]byte{}); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := stronghold.SignSecp256k1Schnorr("eth", make([]byte, 32)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEthereumAddress(t *testing.T) {
+	stronghold, dbPath := initializeStrongholdTest(t, true)
+	stronghold.Create(dbPath)
+
+	stronghold.GenerateSecp256k1KeyPair("eth")
+	address, err := stronghold.EthereumAddress("eth")
 
 	if err != nil {
 		t.Error(err)
@@ -169,6 +353,97 @@ func TestGetAddressFromDerivedSeed(t *testing.T) {
 	t.Log(address)
 }
 
+func TestConcurrentSignOnDifferentKeys(t *testing.T) {
+	stronghold, dbPath := initializeStrongholdTest(t, true)
+	stronghold.Create(dbPath)
+
+	stronghold.GenerateED25519KeyPair("one")
+	stronghold.GenerateED25519KeyPair("two")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+
+	for _, recordPath := range []string{"one", "two"} {
+		wg.Add(1)
+		go func(recordPath string) {
+			defer wg.Done()
+			if _, err := stronghold.Sign(recordPath, make([]byte, 32)); err != nil {
+				errs <- err
+			}
+		}(recordPath)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestSignContextCancellation(t *testing.T) {
+	stronghold, dbPath := initializeStrongholdTest(t, true)
+	stronghold.Create(dbPath)
+	stronghold.GenerateED25519KeyPair("test")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := stronghold.SignContext(ctx, "test", make([]byte, 32)); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// TestCloseWaitsForInFlightOperations exercises the case where a *Context
+// call's ctx is cancelled while its transport call is still running:
+// SignContext must return promptly, but Close must still block until that
+// orphaned transport call actually finishes, rather than destroying the
+// handle out from under it.
+func TestCloseWaitsForInFlightOperations(t *testing.T) {
+	transport := newBlockingFakeTransport()
+	stronghold := stronghold_go.NewStronghold([]byte(testPassword), stronghold_go.WithTransport(transport))
+	stronghold.Create(getNewDBPath())
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signDone := make(chan struct{})
+	go func() {
+		defer close(signDone)
+		stronghold.SignContext(ctx, "test", make([]byte, 32))
+	}()
+
+	<-transport.started
+	cancel()
+
+	select {
+	case <-signDone:
+	case <-time.After(time.Second):
+		t.Fatal("SignContext did not return promptly after cancellation")
+	}
+
+	closeDone := make(chan struct{})
+	go func() {
+		defer close(closeDone)
+		if _, err := stronghold.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	select {
+	case <-closeDone:
+		t.Fatal("Close returned while the cancelled Sign call was still in flight")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(transport.release)
+
+	select {
+	case <-closeDone:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return after the in-flight Sign call finished")
+	}
+}
+
 func TestErrorInvalidPath(t *testing.T) {
 	stronghold := stronghold_go.NewStronghold("foobar")
 	_, err := stronghold.Open("ThisPathDoesNotExist")
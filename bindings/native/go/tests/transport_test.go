@@ -0,0 +1,133 @@
+// Copyright 2020-2022 IOTA Stiftung
+// SPDX-License-Identifier: Apache-2.0
+package tests
+
+import (
+	stronghold_go "stronghold"
+	"testing"
+)
+
+// fakeTransport is an in-memory stronghold_go.Transport used to exercise
+// WithTransport without depending on the cgo core or a running sidecar.
+type fakeTransport struct {
+	opened bool
+}
+
+func (t *fakeTransport) CreateSnapshot(snapshotPath string, key string, cancelToken uint64) (stronghold_go.SessionHandle, error) {
+	t.opened = true
+	return "fake-handle", nil
+}
+
+func (t *fakeTransport) LoadSnapshot(snapshotPath string, key string, cancelToken uint64) (stronghold_go.SessionHandle, error) {
+	return t.CreateSnapshot(snapshotPath, key, cancelToken)
+}
+
+func (t *fakeTransport) GenerateED25519KeyPair(stronghold_go.SessionHandle, string, string) ([stronghold_go.PublicKeySize]byte, error) {
+	return [stronghold_go.PublicKeySize]byte{}, nil
+}
+
+func (t *fakeTransport) Sign(stronghold_go.SessionHandle, string, []byte) ([stronghold_go.SignatureSize]byte, error) {
+	return [stronghold_go.SignatureSize]byte{}, nil
+}
+
+func (t *fakeTransport) GetPublicKey(stronghold_go.SessionHandle, string) ([stronghold_go.PublicKeySize]byte, error) {
+	return [stronghold_go.PublicKeySize]byte{}, nil
+}
+
+func (t *fakeTransport) GenerateSeed(stronghold_go.SessionHandle, string) (bool, error) {
+	return true, nil
+}
+
+func (t *fakeTransport) DeriveSeed(stronghold_go.SessionHandle, string, uint32) (bool, error) {
+	return true, nil
+}
+
+func (t *fakeTransport) GenerateMnemonic(int) (string, error) {
+	return "", nil
+}
+
+func (t *fakeTransport) ImportMnemonic(stronghold_go.SessionHandle, string, string, string, string) error {
+	return nil
+}
+
+func (t *fakeTransport) ExportMnemonic(stronghold_go.SessionHandle, string, string) (string, error) {
+	return "", nil
+}
+
+func (t *fakeTransport) DerivePath(stronghold_go.SessionHandle, string, string, []uint32, string) error {
+	return nil
+}
+
+func (t *fakeTransport) ExecuteProcedure(stronghold_go.SessionHandle, string, []stronghold_go.ProcedureStep, uint64) ([]stronghold_go.StepResult, error) {
+	return nil, nil
+}
+
+func (t *fakeTransport) ChangePassword(stronghold_go.SessionHandle, string, string, uint64) error {
+	return nil
+}
+
+func (t *fakeTransport) X25519SharedSecret(stronghold_go.SessionHandle, string, string, []byte) ([32]byte, error) {
+	return [32]byte{}, nil
+}
+
+func (t *fakeTransport) GenerateSecp256k1KeyPair(stronghold_go.SessionHandle, string, string) ([stronghold_go.PublicKeySizeSecp256k1]byte, error) {
+	return [stronghold_go.PublicKeySizeSecp256k1]byte{}, nil
+}
+
+func (t *fakeTransport) GetPublicKeySecp256k1(stronghold_go.SessionHandle, string) ([stronghold_go.PublicKeySizeSecp256k1]byte, error) {
+	return [stronghold_go.PublicKeySizeSecp256k1]byte{}, nil
+}
+
+func (t *fakeTransport) SignSecp256k1ECDSA(stronghold_go.SessionHandle, string, [32]byte) ([stronghold_go.SignatureSizeSecp256k1]byte, error) {
+	return [stronghold_go.SignatureSizeSecp256k1]byte{}, nil
+}
+
+func (t *fakeTransport) SignSecp256k1Schnorr(stronghold_go.SessionHandle, string, []byte) ([64]byte, error) {
+	return [64]byte{}, nil
+}
+
+func (t *fakeTransport) Cancel(stronghold_go.SessionHandle, uint64) {}
+
+func (t *fakeTransport) Destroy(stronghold_go.SessionHandle) {}
+
+// blockingFakeTransport wraps fakeTransport and makes Sign observable and
+// controllable from a test: it signals started once it has been entered,
+// then blocks until release is closed, so a test can assert something
+// about the period during which the call is genuinely in flight.
+type blockingFakeTransport struct {
+	fakeTransport
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingFakeTransport() *blockingFakeTransport {
+	return &blockingFakeTransport{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (t *blockingFakeTransport) Sign(stronghold_go.SessionHandle, string, []byte) ([stronghold_go.SignatureSize]byte, error) {
+	close(t.started)
+	<-t.release
+	return [stronghold_go.SignatureSize]byte{}, nil
+}
+
+func TestWithTransportOverridesDefault(t *testing.T) {
+	fake := &fakeTransport{}
+	stronghold := stronghold_go.NewStronghold([]byte(testPassword), stronghold_go.WithTransport(fake))
+
+	success, err := stronghold.Create(getNewDBPath())
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !success {
+		t.Error("Expected Create to succeed with a custom transport")
+	}
+
+	if !fake.opened {
+		t.Error("Expected the custom transport's CreateSnapshot to be called")
+	}
+}
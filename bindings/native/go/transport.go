@@ -0,0 +1,54 @@
+// Copyright 2020-2022 IOTA Stiftung
+// SPDX-License-Identifier: Apache-2.0
+package stronghold
+
+// SessionHandle identifies an open snapshot for a Transport. Its concrete
+// type is owned by the Transport implementation (e.g. a cgo pointer or an
+// IPC session id) and must not be inspected by callers.
+type SessionHandle interface{}
+
+// Transport is the boundary between StrongholdNative and whatever runs the
+// Stronghold core. The default in a cgo build, cgoTransport, links the Rust
+// core in-process (a CGO_ENABLED=0 build defaults to noTransport, which
+// fails every call);
+// ipcTransport speaks to an out-of-process stronghold-native sidecar for
+// callers that cannot or do not want to cgo-link the native library.
+type Transport interface {
+	CreateSnapshot(snapshotPath string, key string, cancelToken uint64) (SessionHandle, error)
+	LoadSnapshot(snapshotPath string, key string, cancelToken uint64) (SessionHandle, error)
+	GenerateED25519KeyPair(handle SessionHandle, key string, recordPath string) ([PublicKeySize]byte, error)
+	Sign(handle SessionHandle, recordPath string, data []byte) ([SignatureSize]byte, error)
+	GetPublicKey(handle SessionHandle, recordPath string) ([PublicKeySize]byte, error)
+	GenerateSeed(handle SessionHandle, key string) (bool, error)
+	DeriveSeed(handle SessionHandle, key string, index uint32) (bool, error)
+	GenerateMnemonic(entropyBits int) (string, error)
+	ImportMnemonic(handle SessionHandle, key string, mnemonic string, passphrase string, recordPath string) error
+	ExportMnemonic(handle SessionHandle, key string, recordPath string) (string, error)
+	DerivePath(handle SessionHandle, key string, recordPath string, path []uint32, childRecordPath string) error
+	ExecuteProcedure(handle SessionHandle, key string, steps []ProcedureStep, cancelToken uint64) ([]StepResult, error)
+	ChangePassword(handle SessionHandle, oldKey string, newKey string, cancelToken uint64) error
+	X25519SharedSecret(handle SessionHandle, key string, recordPath string, peerPublicKey []byte) ([32]byte, error)
+	GenerateSecp256k1KeyPair(handle SessionHandle, key string, recordPath string) ([PublicKeySizeSecp256k1]byte, error)
+	GetPublicKeySecp256k1(handle SessionHandle, recordPath string) ([PublicKeySizeSecp256k1]byte, error)
+	SignSecp256k1ECDSA(handle SessionHandle, recordPath string, digest [32]byte) ([SignatureSizeSecp256k1]byte, error)
+	SignSecp256k1Schnorr(handle SessionHandle, recordPath string, msg []byte) ([64]byte, error)
+	// Cancel asks the core to abort the in-flight operation identified by
+	// cancelToken, e.g. an Argon2id snapshot decryption. handle may be nil
+	// when cancelling a CreateSnapshot/LoadSnapshot call that has not
+	// produced a handle yet.
+	Cancel(handle SessionHandle, cancelToken uint64)
+	Destroy(handle SessionHandle)
+}
+
+// StrongholdOption configures a StrongholdNative at construction time.
+type StrongholdOption func(*StrongholdNative)
+
+// WithTransport overrides the default transport (cgoTransport in a cgo
+// build, noTransport otherwise), e.g. to route calls to
+// an out-of-process stronghold-native sidecar instead of linking against
+// libstronghold_native directly.
+func WithTransport(transport Transport) StrongholdOption {
+	return func(s *StrongholdNative) {
+		s.transport = transport
+	}
+}
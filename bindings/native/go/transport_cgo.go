@@ -0,0 +1,119 @@
+// Copyright 2020-2022 IOTA Stiftung
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build cgo
+
+package stronghold
+
+// cgoTransport is the default Transport: it links libstronghold_native
+// in-process via cgo. See native.go for the C bindings it wraps.
+type cgoTransport struct{}
+
+func (cgoTransport) CreateSnapshot(snapshotPath string, key string, cancelToken uint64) (SessionHandle, error) {
+	ptr, err := createSnapshot(snapshotPath, key, cancelToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return ptr, nil
+}
+
+func (cgoTransport) LoadSnapshot(snapshotPath string, key string, cancelToken uint64) (SessionHandle, error) {
+	ptr, err := loadSnapshot(snapshotPath, key, cancelToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return ptr, nil
+}
+
+func (cgoTransport) GenerateED25519KeyPair(handle SessionHandle, key string, recordPath string) ([PublicKeySize]byte, error) {
+	return generateED25519KeyPair(handle.(StrongholdPointer), key, recordPath)
+}
+
+func (cgoTransport) Sign(handle SessionHandle, recordPath string, data []byte) ([SignatureSize]byte, error) {
+	return sign(handle.(StrongholdPointer), recordPath, data)
+}
+
+func (cgoTransport) GetPublicKey(handle SessionHandle, recordPath string) ([PublicKeySize]byte, error) {
+	return getPublicKey(handle.(StrongholdPointer), recordPath)
+}
+
+func (cgoTransport) GenerateSeed(handle SessionHandle, key string) (bool, error) {
+	return generateSeed(handle.(StrongholdPointer), key)
+}
+
+func (cgoTransport) DeriveSeed(handle SessionHandle, key string, index uint32) (bool, error) {
+	return deriveSeed(handle.(StrongholdPointer), key, index)
+}
+
+func (cgoTransport) GenerateMnemonic(entropyBits int) (string, error) {
+	return generateMnemonic(entropyBits)
+}
+
+func (cgoTransport) ImportMnemonic(handle SessionHandle, key string, mnemonic string, passphrase string, recordPath string) error {
+	_, err := importMnemonic(handle.(StrongholdPointer), key, mnemonic, passphrase, recordPath)
+	return err
+}
+
+func (cgoTransport) ExportMnemonic(handle SessionHandle, key string, recordPath string) (string, error) {
+	return exportMnemonic(handle.(StrongholdPointer), key, recordPath)
+}
+
+func (cgoTransport) DerivePath(handle SessionHandle, key string, recordPath string, path []uint32, childRecordPath string) error {
+	_, err := derivePath(handle.(StrongholdPointer), key, recordPath, path, childRecordPath)
+	return err
+}
+
+func (cgoTransport) ExecuteProcedure(handle SessionHandle, key string, steps []ProcedureStep, cancelToken uint64) ([]StepResult, error) {
+	requestData, err := encodeCBOR(steps)
+	if err != nil {
+		return nil, err
+	}
+
+	responseData, err := executeProcedure(handle.(StrongholdPointer), key, requestData, cancelToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []StepResult
+	if err := decodeCBOR(responseData, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (cgoTransport) ChangePassword(handle SessionHandle, oldKey string, newKey string, cancelToken uint64) error {
+	_, err := changePassword(handle.(StrongholdPointer), oldKey, newKey, cancelToken)
+	return err
+}
+
+func (cgoTransport) X25519SharedSecret(handle SessionHandle, key string, recordPath string, peerPublicKey []byte) ([32]byte, error) {
+	return x25519DiffieHellman(handle.(StrongholdPointer), key, recordPath, peerPublicKey)
+}
+
+func (cgoTransport) GenerateSecp256k1KeyPair(handle SessionHandle, key string, recordPath string) ([PublicKeySizeSecp256k1]byte, error) {
+	return generateSecp256k1KeyPair(handle.(StrongholdPointer), key, recordPath)
+}
+
+func (cgoTransport) GetPublicKeySecp256k1(handle SessionHandle, recordPath string) ([PublicKeySizeSecp256k1]byte, error) {
+	return getPublicKeySecp256k1(handle.(StrongholdPointer), recordPath)
+}
+
+func (cgoTransport) SignSecp256k1ECDSA(handle SessionHandle, recordPath string, digest [32]byte) ([SignatureSizeSecp256k1]byte, error) {
+	return signSecp256k1ECDSA(handle.(StrongholdPointer), recordPath, digest)
+}
+
+func (cgoTransport) SignSecp256k1Schnorr(handle SessionHandle, recordPath string, msg []byte) ([64]byte, error) {
+	return signSecp256k1Schnorr(handle.(StrongholdPointer), recordPath, msg)
+}
+
+func (cgoTransport) Cancel(handle SessionHandle, cancelToken uint64) {
+	ptr, _ := handle.(StrongholdPointer)
+	cancel(ptr, cancelToken)
+}
+
+func (cgoTransport) Destroy(handle SessionHandle) {
+	destroyStronghold(handle.(StrongholdPointer))
+}
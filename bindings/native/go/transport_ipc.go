@@ -0,0 +1,297 @@
+// Copyright 2020-2022 IOTA Stiftung
+// SPDX-License-Identifier: Apache-2.0
+package stronghold
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// ipcTransport speaks a length-prefixed request/response protocol to a
+// stronghold-native sidecar process over a Unix domain socket, so that Go
+// consumers can deploy Stronghold without cgo-linking libstronghold_native
+// or shipping its .so/.dylib. Each request is a 4-byte big-endian length
+// prefix followed by a CBOR-encoded ipcRequest; each response is framed the
+// same way around an ipcResponse.
+type ipcTransport struct {
+	mu   sync.Mutex
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+// NewIPCTransport dials a running stronghold-native sidecar listening on a
+// Unix domain socket at socketPath.
+func NewIPCTransport(socketPath string) (Transport, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("stronghold: failed to dial sidecar at %s: %w", socketPath, err)
+	}
+
+	return &ipcTransport{
+		conn: conn,
+		rw:   bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)),
+	}, nil
+}
+
+type ipcSessionHandle uint64
+
+type ipcRequest struct {
+	Method          string
+	SnapshotPath    string
+	Key             string
+	RecordPath      string
+	ChildRecordPath string
+	Data            []byte
+	Index           uint32
+	EntropyBits     int
+	Mnemonic        string
+	Passphrase      string
+	Path            []uint32
+	Steps           []ProcedureStep
+	OldKey          string
+	NewKey          string
+	PeerPublicKey   []byte
+	Digest          []byte
+	Handle          ipcSessionHandle
+	CancelToken     uint64
+}
+
+type ipcResponse struct {
+	Handle       ipcSessionHandle
+	PublicKey    []byte
+	Signature    []byte
+	Mnemonic     string
+	Results      []StepResult
+	SharedSecret []byte
+	Ok           bool
+	ErrMessage   string
+}
+
+func (t *ipcTransport) call(req ipcRequest) (ipcResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	payload, err := encodeCBOR(req)
+	if err != nil {
+		return ipcResponse{}, err
+	}
+
+	if err := binary.Write(t.rw, binary.BigEndian, uint32(len(payload))); err != nil {
+		return ipcResponse{}, fmt.Errorf("stronghold: failed to write request frame: %w", err)
+	}
+
+	if _, err := t.rw.Write(payload); err != nil {
+		return ipcResponse{}, fmt.Errorf("stronghold: failed to write request body: %w", err)
+	}
+
+	if err := t.rw.Flush(); err != nil {
+		return ipcResponse{}, fmt.Errorf("stronghold: failed to flush request: %w", err)
+	}
+
+	var length uint32
+	if err := binary.Read(t.rw, binary.BigEndian, &length); err != nil {
+		return ipcResponse{}, fmt.Errorf("stronghold: failed to read response frame: %w", err)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(t.rw, body); err != nil {
+		return ipcResponse{}, fmt.Errorf("stronghold: failed to read response body: %w", err)
+	}
+
+	var resp ipcResponse
+	if err := decodeCBOR(body, &resp); err != nil {
+		return ipcResponse{}, err
+	}
+
+	if !resp.Ok {
+		return ipcResponse{}, fmt.Errorf("stronghold: sidecar error: %s", resp.ErrMessage)
+	}
+
+	return resp, nil
+}
+
+func (t *ipcTransport) CreateSnapshot(snapshotPath string, key string, cancelToken uint64) (SessionHandle, error) {
+	resp, err := t.call(ipcRequest{Method: "CreateSnapshot", SnapshotPath: snapshotPath, Key: key, CancelToken: cancelToken})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Handle, nil
+}
+
+func (t *ipcTransport) LoadSnapshot(snapshotPath string, key string, cancelToken uint64) (SessionHandle, error) {
+	resp, err := t.call(ipcRequest{Method: "LoadSnapshot", SnapshotPath: snapshotPath, Key: key, CancelToken: cancelToken})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Handle, nil
+}
+
+func (t *ipcTransport) GenerateED25519KeyPair(handle SessionHandle, key string, recordPath string) ([PublicKeySize]byte, error) {
+	resp, err := t.call(ipcRequest{Method: "GenerateED25519KeyPair", Handle: handle.(ipcSessionHandle), Key: key, RecordPath: recordPath})
+	if err != nil {
+		return [PublicKeySize]byte{}, err
+	}
+
+	var publicKey [PublicKeySize]byte
+	copy(publicKey[:], resp.PublicKey)
+
+	return publicKey, nil
+}
+
+func (t *ipcTransport) Sign(handle SessionHandle, recordPath string, data []byte) ([SignatureSize]byte, error) {
+	resp, err := t.call(ipcRequest{Method: "Sign", Handle: handle.(ipcSessionHandle), RecordPath: recordPath, Data: data})
+	if err != nil {
+		return [SignatureSize]byte{}, err
+	}
+
+	var signature [SignatureSize]byte
+	copy(signature[:], resp.Signature)
+
+	return signature, nil
+}
+
+func (t *ipcTransport) GetPublicKey(handle SessionHandle, recordPath string) ([PublicKeySize]byte, error) {
+	resp, err := t.call(ipcRequest{Method: "GetPublicKey", Handle: handle.(ipcSessionHandle), RecordPath: recordPath})
+	if err != nil {
+		return [PublicKeySize]byte{}, err
+	}
+
+	var publicKey [PublicKeySize]byte
+	copy(publicKey[:], resp.PublicKey)
+
+	return publicKey, nil
+}
+
+func (t *ipcTransport) GenerateSeed(handle SessionHandle, key string) (bool, error) {
+	_, err := t.call(ipcRequest{Method: "GenerateSeed", Handle: handle.(ipcSessionHandle), Key: key})
+	return err == nil, err
+}
+
+func (t *ipcTransport) DeriveSeed(handle SessionHandle, key string, index uint32) (bool, error) {
+	_, err := t.call(ipcRequest{Method: "DeriveSeed", Handle: handle.(ipcSessionHandle), Key: key, Index: index})
+	return err == nil, err
+}
+
+func (t *ipcTransport) GenerateMnemonic(entropyBits int) (string, error) {
+	resp, err := t.call(ipcRequest{Method: "GenerateMnemonic", EntropyBits: entropyBits})
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Mnemonic, nil
+}
+
+func (t *ipcTransport) ImportMnemonic(handle SessionHandle, key string, mnemonic string, passphrase string, recordPath string) error {
+	_, err := t.call(ipcRequest{Method: "ImportMnemonic", Handle: handle.(ipcSessionHandle), Key: key, Mnemonic: mnemonic, Passphrase: passphrase, RecordPath: recordPath})
+	return err
+}
+
+func (t *ipcTransport) ExportMnemonic(handle SessionHandle, key string, recordPath string) (string, error) {
+	resp, err := t.call(ipcRequest{Method: "ExportMnemonic", Handle: handle.(ipcSessionHandle), Key: key, RecordPath: recordPath})
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Mnemonic, nil
+}
+
+func (t *ipcTransport) DerivePath(handle SessionHandle, key string, recordPath string, path []uint32, childRecordPath string) error {
+	_, err := t.call(ipcRequest{Method: "DerivePath", Handle: handle.(ipcSessionHandle), Key: key, RecordPath: recordPath, Path: path, ChildRecordPath: childRecordPath})
+	return err
+}
+
+func (t *ipcTransport) ExecuteProcedure(handle SessionHandle, key string, steps []ProcedureStep, cancelToken uint64) ([]StepResult, error) {
+	resp, err := t.call(ipcRequest{Method: "ExecuteProcedure", Handle: handle.(ipcSessionHandle), Key: key, Steps: steps, CancelToken: cancelToken})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Results, nil
+}
+
+func (t *ipcTransport) ChangePassword(handle SessionHandle, oldKey string, newKey string, cancelToken uint64) error {
+	_, err := t.call(ipcRequest{Method: "ChangePassword", Handle: handle.(ipcSessionHandle), OldKey: oldKey, NewKey: newKey, CancelToken: cancelToken})
+	return err
+}
+
+// Cancel asks the sidecar to abort the in-flight operation identified by
+// cancelToken. handle may be the zero ipcSessionHandle when cancelling a
+// CreateSnapshot/LoadSnapshot call that has not produced a handle yet.
+// The Cancel request goes over the same connection under t.mu, so it is
+// only sent after any call already in progress on this transport has
+// returned; it does not interrupt that call.
+func (t *ipcTransport) Cancel(handle SessionHandle, cancelToken uint64) {
+	sessionHandle, _ := handle.(ipcSessionHandle)
+	_, _ = t.call(ipcRequest{Method: "Cancel", Handle: sessionHandle, CancelToken: cancelToken})
+}
+
+func (t *ipcTransport) X25519SharedSecret(handle SessionHandle, key string, recordPath string, peerPublicKey []byte) ([32]byte, error) {
+	resp, err := t.call(ipcRequest{Method: "X25519SharedSecret", Handle: handle.(ipcSessionHandle), Key: key, RecordPath: recordPath, PeerPublicKey: peerPublicKey})
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	var sharedSecret [32]byte
+	copy(sharedSecret[:], resp.SharedSecret)
+
+	return sharedSecret, nil
+}
+
+func (t *ipcTransport) GenerateSecp256k1KeyPair(handle SessionHandle, key string, recordPath string) ([PublicKeySizeSecp256k1]byte, error) {
+	resp, err := t.call(ipcRequest{Method: "GenerateSecp256k1KeyPair", Handle: handle.(ipcSessionHandle), Key: key, RecordPath: recordPath})
+	if err != nil {
+		return [PublicKeySizeSecp256k1]byte{}, err
+	}
+
+	var publicKey [PublicKeySizeSecp256k1]byte
+	copy(publicKey[:], resp.PublicKey)
+
+	return publicKey, nil
+}
+
+func (t *ipcTransport) GetPublicKeySecp256k1(handle SessionHandle, recordPath string) ([PublicKeySizeSecp256k1]byte, error) {
+	resp, err := t.call(ipcRequest{Method: "GetPublicKeySecp256k1", Handle: handle.(ipcSessionHandle), RecordPath: recordPath})
+	if err != nil {
+		return [PublicKeySizeSecp256k1]byte{}, err
+	}
+
+	var publicKey [PublicKeySizeSecp256k1]byte
+	copy(publicKey[:], resp.PublicKey)
+
+	return publicKey, nil
+}
+
+func (t *ipcTransport) SignSecp256k1ECDSA(handle SessionHandle, recordPath string, digest [32]byte) ([SignatureSizeSecp256k1]byte, error) {
+	resp, err := t.call(ipcRequest{Method: "SignSecp256k1ECDSA", Handle: handle.(ipcSessionHandle), RecordPath: recordPath, Digest: digest[:]})
+	if err != nil {
+		return [SignatureSizeSecp256k1]byte{}, err
+	}
+
+	var signature [SignatureSizeSecp256k1]byte
+	copy(signature[:], resp.Signature)
+
+	return signature, nil
+}
+
+func (t *ipcTransport) SignSecp256k1Schnorr(handle SessionHandle, recordPath string, msg []byte) ([64]byte, error) {
+	resp, err := t.call(ipcRequest{Method: "SignSecp256k1Schnorr", Handle: handle.(ipcSessionHandle), RecordPath: recordPath, Data: msg})
+	if err != nil {
+		return [64]byte{}, err
+	}
+
+	var signature [64]byte
+	copy(signature[:], resp.Signature)
+
+	return signature, nil
+}
+
+func (t *ipcTransport) Destroy(handle SessionHandle) {
+	_, _ = t.call(ipcRequest{Method: "Destroy", Handle: handle.(ipcSessionHandle)})
+	_ = t.conn.Close()
+}
@@ -0,0 +1,31 @@
+// Copyright 2020-2022 IOTA Stiftung
+// SPDX-License-Identifier: Apache-2.0
+package stronghold
+
+import (
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// encodeCBOR and decodeCBOR are the wire format for every message that
+// crosses into the Rust stronghold-native core or sidecar, whether over
+// cgo (ExecuteProcedure's step list) or the IPC transport (every
+// request/response). CBOR, rather than encoding/gob, is used because gob
+// is a Go-only format the Rust side cannot decode.
+func encodeCBOR(v interface{}) ([]byte, error) {
+	data, err := cbor.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("stronghold: failed to encode CBOR message: %w", err)
+	}
+
+	return data, nil
+}
+
+func decodeCBOR(data []byte, v interface{}) error {
+	if err := cbor.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("stronghold: failed to decode CBOR message: %w", err)
+	}
+
+	return nil
+}